@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/dhouti/tsymbiote/api/adapter/internal"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
 	"github.com/gorilla/websocket"
 )
 
@@ -16,14 +18,17 @@ func (t *TSymbioteAdapterServer) BusEvents(w http.ResponseWriter, r *tsymbiote.H
 	wsDeathCtx, wsDeathFunc := context.WithCancel(context.Background())
 	wsMessage := make(chan tsymbiote.WebsocketMessage)
 
+	// Filters are passed as query params, the eventbus is chatty so most clients only want a few types.
+	filter := types.NewBusEventFilter(r.URL.Query())
+
 	// Run goroutines using manager so we can inject a non-request scoped context and signal/track shutdown events.
 	t.RunWSFunc(internal.WebsocketReader(wsDeathCtx, wsDeathFunc, r))
 	t.RunWSFunc(internal.WebsocketWriter(wsDeathCtx, r, wsMessage))
-	t.RunWSFunc(t.busEventsIterFunc(wsDeathCtx, r, wsMessage))
+	t.RunWSFunc(t.busEventsIterFunc(wsDeathCtx, r, filter, wsMessage))
 }
 
 // Read from bus events and write back to the websocket writer from the iterator.
-func (t *TSymbioteAdapterServer) busEventsIterFunc(wsDeathCtx context.Context, r *tsymbiote.HTTPRequest, wsMessage chan tsymbiote.WebsocketMessage) tsymbiote.WebsocketFunc {
+func (t *TSymbioteAdapterServer) busEventsIterFunc(wsDeathCtx context.Context, r *tsymbiote.HTTPRequest, filter *types.BusEventFilter, wsMessage chan tsymbiote.WebsocketMessage) tsymbiote.WebsocketFunc {
 	return func(wsReaderCtx context.Context) {
 		// Uses wsDeathCtx so when sockets die the scanner emits False and breaks the loop.
 		busEvents := t.Host().StreamBusEvents(wsDeathCtx)
@@ -36,7 +41,19 @@ func (t *TSymbioteAdapterServer) busEventsIterFunc(wsDeathCtx context.Context, r
 				return
 			}
 
-			out, err := json.Marshal(event)
+			if !filter.Match(event.Type, event.From) {
+				continue
+			}
+
+			// Wrap in our own envelope so clients aren't coupled to eventbus.DebugEvent.
+			out, err := json.Marshal(types.BusEvent{
+				Timestamp:   time.Now(),
+				Count:       event.Count,
+				Type:        event.Type,
+				Publisher:   event.From,
+				Subscribers: event.To,
+				Payload:     event.Event,
+			})
 			if err != nil {
 				r.Log.Errorw("failed to marshal debug event", "error", err)
 				internal.CloseWebsocket(r)
@@ -0,0 +1,126 @@
+package tsymbioteadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/client/local"
+	"tailscale.com/tailcfg"
+)
+
+// derpProbeParallelism bounds concurrent region checks so they don't skew each other's check duration.
+const derpProbeParallelism = 4
+
+func (t *TSymbioteAdapterServer) BugReport(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &types.BugReportInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode bugreport input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	marker, err := t.Host().BugReportWithOpts(r.Context(), local.BugReportOpts{
+		Note:     input.Note,
+		Diagnose: input.Diagnose,
+	})
+	if err != nil {
+		r.Log.Errorw("failed to create bugreport", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	r.Log.Infow("created bugreport", "marker", marker)
+	t.WriteJson(w, r, types.BugReportResult{Marker: marker})
+}
+
+func (t *TSymbioteAdapterServer) DERPMap(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	derpMap, err := t.Host().CurrentDERPMap(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get derp map", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	t.WriteJson(w, r, derpMap)
+}
+
+// DERPRegions reports each requested region's latency from tailscaled's last netcheck
+// and runs the debug DERP region check against it.
+func (t *TSymbioteAdapterServer) DERPRegions(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &types.DERPProbeInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode derp probe input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	derpMap, err := t.Host().CurrentDERPMap(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get derp map", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	var regions []*tailcfg.DERPRegion
+	for _, regionID := range derpMap.RegionIDs() {
+		region := derpMap.Regions[regionID]
+		if len(input.Regions) == 0 || slices.Contains(input.Regions, region.RegionCode) || slices.Contains(input.Regions, strconv.Itoa(regionID)) {
+			regions = append(regions, region)
+		}
+	}
+
+	// Latency is best effort, a node that hasn't finished a netcheck still gets its region checks.
+	netInfo, err := t.selfNetInfo(r.Context())
+	if err != nil {
+		r.Log.Infow("failed to read netcheck result from netmap", "error", err)
+	}
+	latency := func(regionID int, family string) time.Duration {
+		if !netInfo.Valid() {
+			return 0
+		}
+		seconds, _ := netInfo.DERPLatency().GetOk(fmt.Sprintf("%d-%s", regionID, family))
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	probes := make([]types.DERPRegionProbe, len(regions))
+	slots := make(chan struct{}, derpProbeParallelism)
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Go(func() {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			probe := types.DERPRegionProbe{
+				RegionID:   region.RegionID,
+				RegionCode: region.RegionCode,
+				RegionName: region.RegionName,
+				LatencyV4:  latency(region.RegionID, "v4"),
+				LatencyV6:  latency(region.RegionID, "v6"),
+			}
+
+			started := time.Now()
+			report, err := t.Host().DebugDERPRegion(r.Context(), strconv.Itoa(region.RegionID))
+			probe.CheckDuration = time.Since(started)
+			if err != nil {
+				probe.Errors = []string{err.Error()}
+			} else {
+				probe.Info = report.Info
+				probe.Warnings = report.Warnings
+				probe.Errors = report.Errors
+			}
+			probes[i] = probe
+		})
+	}
+	wg.Wait()
+
+	t.WriteJson(w, r, probes)
+}
@@ -0,0 +1,215 @@
+package tsymbioteadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/adapter/internal"
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"tailscale.com/client/local"
+)
+
+// hostHandler is a handler that runs against one tailscaled, it's registered for the root and for every instance.
+type hostHandler func(*TSymbioteAdapterServer, http.ResponseWriter, *tsymbiote.HTTPRequest)
+
+// loadInstances returns the sockets to serve, if --socket and --discover-socket prefer discovery.
+// Discovered instances are named by their tailscaled's hostname so the name survives a restart under a new PID.
+func loadInstances(ctx context.Context, log *zap.SugaredLogger) ([]types.HostInstance, error) {
+	if !viper.GetBool("discover-socket") {
+		return types.ParseHostInstances(viper.GetStringSlice("socket"))
+	}
+
+	sockets, err := utils.DiscoverSockets(func(pid string, err error) {
+		log.Infow("skipping tailscale socket", "pid", pid, "error", err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]types.HostInstance, 0, len(sockets))
+	for _, socket := range sockets {
+		name, err := socketHostName(ctx, socket.Path)
+		if err != nil {
+			log.Infow("failed to read tailscale hostname, naming instance by PID", "pid", socket.PID, "error", err)
+			name = socket.PID
+		}
+		// Hostnames aren't unique, IE: two userspace instances left at the machine's hostname.
+		if slices.ContainsFunc(instances, func(instance types.HostInstance) bool { return instance.Name == name }) {
+			name = name + "-" + socket.PID
+		}
+		instances = append(instances, types.HostInstance{Name: name, Socket: socket.Path, OtherNetns: socket.OtherNetns})
+	}
+	return instances, nil
+}
+
+// socketHostName asks the tailscaled behind a socket for its hostname.
+func socketHostName(ctx context.Context, socket string) (string, error) {
+	statusCtx, statusCancel := context.WithTimeout(ctx, consts.OutgoingRequestTimeout)
+	defer statusCancel()
+
+	status, err := (&local.Client{Socket: socket}).StatusWithoutPeers(statusCtx)
+	if err != nil {
+		return "", err
+	}
+	if status.Self == nil || status.Self.HostName == "" {
+		return "", errors.New("tailscaled has no hostname yet")
+	}
+	// The name is a path segment.
+	if strings.Contains(status.Self.HostName, "/") {
+		return "", fmt.Errorf("hostname %q can't name an instance", status.Self.HostName)
+	}
+	return status.Self.HostName, nil
+}
+
+// setInstances swaps in a new set of instances, unchanged instances keep their known peers.
+func (t *TSymbioteAdapterServer) setInstances(instances []types.HostInstance) {
+	current := map[string]bool{}
+	for _, instance := range instances {
+		current[instance.Name] = true
+
+		existing, ok := t.getInstance(instance.Name)
+		if ok && existing.Host().Socket == instance.Socket {
+			continue
+		}
+
+		server := &TSymbioteAdapterServer{
+			TSymbioteServer: t.TSymbioteServer,
+			allowedTag:      t.allowedTag,
+			relayToken:      t.relayToken,
+			KnownPeers:      &internal.KnownPeers{},
+		}
+		server.host.Store(&hostSocket{client: &local.Client{Socket: instance.Socket}, otherNetns: instance.OtherNetns})
+		t.instances.Store(instance.Name, server)
+	}
+
+	t.instances.Range(func(k, v any) bool {
+		if !current[k.(string)] {
+			t.instances.Delete(k)
+		}
+		return true
+	})
+}
+
+func (t *TSymbioteAdapterServer) getInstance(name string) (*TSymbioteAdapterServer, bool) {
+	val, ok := t.instances.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return val.(*TSymbioteAdapterServer), ok
+}
+
+// getInstances returns every instance sorted by name.
+func (t *TSymbioteAdapterServer) getInstances() []types.HostInstance {
+	instances := []types.HostInstance{}
+	t.instances.Range(func(k, v any) bool {
+		instance := v.(*TSymbioteAdapterServer)
+		host := instance.host.Load()
+		instances = append(instances, types.HostInstance{Name: k.(string), Socket: host.client.Socket, OtherNetns: host.otherNetns})
+		return true
+	})
+	slices.SortFunc(instances, func(a, b types.HostInstance) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return instances
+}
+
+// rediscoverSockets follows tailscaled coming and going, IE: ProxyGroup pods scheduled onto the node.
+// The root keeps serving its socket while it's still discovered, see setRoot.
+func (t *TSymbioteAdapterServer) rediscoverSockets(shutdownCtx context.Context) {
+	ticker := time.NewTicker(consts.SocketDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		instances, err := loadInstances(shutdownCtx, t.Log)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// Discovery already waited out a restarting tailscaled, every socket is gone.
+			t.Log.Infow("no tailscale sockets left, dropping instances")
+			t.setInstances(nil)
+		case err != nil:
+			// Keep what we have, we couldn't look rather than found nothing.
+			t.Log.Infow("failed to rediscover tailscale sockets", "error", err)
+		default:
+			t.setInstances(instances)
+			t.setRoot(instances)
+		}
+	}
+}
+
+// setRoot moves the root to the first instance once its socket is no longer discovered, IE: its tailscaled restarted under a new PID.
+// Without it the root would keep calling a socket that's gone, or a PID that now belongs to another process.
+func (t *TSymbioteAdapterServer) setRoot(instances []types.HostInstance) {
+	root := t.host.Load()
+	if len(instances) == 0 || slices.ContainsFunc(instances, func(instance types.HostInstance) bool {
+		return instance.Socket == root.client.Socket && instance.OtherNetns == root.otherNetns
+	}) {
+		return
+	}
+
+	t.Log.Infow("root tailscale socket is gone, serving the first instance at the root", "previous", root.client.Socket, "socket", instances[0].Socket)
+	t.host.Store(&hostSocket{client: &local.Client{Socket: instances[0].Socket}, otherNetns: instances[0].OtherNetns})
+}
+
+// Instances lists the tailscaled served under /instance/<name>.
+// It's empty when there is only one, the WebUI then keeps calling the adapter at the root.
+func (t *TSymbioteAdapterServer) Instances(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	instances := t.getInstances()
+	if len(instances) < 2 {
+		instances = []types.HostInstance{}
+	}
+	t.WriteJson(w, r, instances)
+}
+
+// hostRoute registers a POST handler at the root and under /instance/<name>.
+func (t *TSymbioteAdapterServer) hostRoute(path paths.KnownPath, handler hostHandler) {
+	t.Route().Post().Register(path.Adapter(), func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+		handler(t, w, r)
+	})
+	t.Route().Post().Register(consts.InstancePathPrefix+"{instance}"+path.Adapter(), t.instanceHandler(handler))
+}
+
+// hostWebsocket is hostRoute for websocket handlers.
+func (t *TSymbioteAdapterServer) hostWebsocket(path paths.KnownPath, handler hostHandler) {
+	t.Route().Websocket().Register(path.Adapter(), func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+		handler(t, w, r)
+	})
+	t.Route().Websocket().Register(consts.InstancePathPrefix+"{instance}"+path.Adapter(), t.instanceHandler(handler))
+}
+
+// instanceHandler runs the handler against the instance named in the path.
+func (t *TSymbioteAdapterServer) instanceHandler(handler hostHandler) tsymbiote.HandlerFunc {
+	return func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+		name := r.PathValue("instance")
+		instance, ok := t.getInstance(name)
+		if !ok {
+			r.Log.Errorw("unknown instance", "instance", name)
+			// Websockets are already upgraded, closing is all we can do.
+			if r.WS != nil {
+				r.WS.Close()
+				return
+			}
+			r.SetStatusCode(w, http.StatusNotFound)
+			return
+		}
+
+		handler(instance, w, r)
+	}
+}
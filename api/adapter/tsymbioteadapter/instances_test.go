@@ -0,0 +1,131 @@
+package tsymbioteadapter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dhouti/tsymbiote/api/adapter/internal"
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"tailscale.com/client/local"
+)
+
+var _ = Describe("Instances", func() {
+	var adapter *TSymbioteAdapterServer
+	var server *httptest.Server
+
+	// call returns the status and body of an adapter call as the relay would make it.
+	call := func(path string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set(consts.RelayTokenHeader, "secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	BeforeEach(func() {
+		adapter = &TSymbioteAdapterServer{
+			TSymbioteServer: &tsymbiote.TSymbioteServer{Log: zap.NewNop().Sugar(), Mux: http.NewServeMux()},
+			relayToken:      "secret",
+			KnownPeers:      &internal.KnownPeers{},
+		}
+		adapter.host.Store(&hostSocket{client: &local.Client{Socket: "/run/root/tailscaled.sock"}})
+		adapter.setInstances([]types.HostInstance{
+			{Name: "a", Socket: "/run/a/tailscaled.sock"},
+			{Name: "b", Socket: "/run/b/tailscaled.sock"},
+		})
+
+		// Answer with the socket the handler was given instead of calling tailscaled.
+		adapter.hostRoute(paths.Status, func(t *TSymbioteAdapterServer, w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+			w.Write([]byte(t.Host().Socket))
+		})
+		adapter.Route().Post().Register(paths.Instances.Adapter(), adapter.Instances)
+
+		server = httptest.NewServer(adapter.Mux)
+		DeferCleanup(server.Close)
+	})
+
+	It("Should run host handlers against the root or the instance in the path", func() {
+		code, body := call(paths.Status.Adapter())
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("/run/root/tailscaled.sock"))
+
+		code, body = call("/instance/b" + paths.Status.Adapter())
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal("/run/b/tailscaled.sock"))
+
+		code, _ = call("/instance/c" + paths.Status.Adapter())
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should list instances only while there are several", func() {
+		_, body := call(paths.Instances.Adapter())
+		var instances []types.HostInstance
+		Expect(json.Unmarshal([]byte(body), &instances)).To(Succeed())
+		Expect(instances).To(Equal([]types.HostInstance{
+			{Name: "a", Socket: "/run/a/tailscaled.sock"},
+			{Name: "b", Socket: "/run/b/tailscaled.sock"},
+		}))
+
+		adapter.setInstances([]types.HostInstance{{Name: "a", Socket: "/run/a/tailscaled.sock"}})
+		_, body = call(paths.Instances.Adapter())
+		Expect(json.Unmarshal([]byte(body), &instances)).To(Succeed())
+		Expect(instances).To(BeEmpty())
+
+		code, _ := call("/instance/b" + paths.Status.Adapter())
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should keep an unchanged instance's known peers", func() {
+		before, ok := adapter.getInstance("a")
+		Expect(ok).To(BeTrue())
+
+		adapter.setInstances([]types.HostInstance{
+			{Name: "a", Socket: "/run/a/tailscaled.sock"},
+			{Name: "b", Socket: "/run/b2/tailscaled.sock"},
+		})
+
+		after, _ := adapter.getInstance("a")
+		Expect(after).To(BeIdenticalTo(before))
+		moved, _ := adapter.getInstance("b")
+		Expect(moved.Host().Socket).To(Equal("/run/b2/tailscaled.sock"))
+	})
+
+	It("Should reject calls without the relay token", func() {
+		resp, err := http.Post(server.URL+paths.Status.Adapter(), "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	It("Should move the root to the first instance once its socket is gone", func() {
+		adapter.setRoot([]types.HostInstance{
+			{Name: "a", Socket: "/run/a/tailscaled.sock"},
+			{Name: "root", Socket: "/run/root/tailscaled.sock"},
+		})
+		Expect(adapter.Host().Socket).To(Equal("/run/root/tailscaled.sock"))
+
+		adapter.setRoot([]types.HostInstance{
+			{Name: "a", Socket: "/run/a/tailscaled.sock", OtherNetns: true},
+			{Name: "b", Socket: "/run/b/tailscaled.sock"},
+		})
+		Expect(adapter.Host().Socket).To(Equal("/run/a/tailscaled.sock"))
+		Expect(adapter.host.Load().otherNetns).To(BeTrue())
+
+		adapter.setRoot(nil)
+		Expect(adapter.Host().Socket).To(Equal("/run/a/tailscaled.sock"))
+	})
+})
@@ -0,0 +1,118 @@
+package tsymbioteadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/client/local"
+	"tailscale.com/ipn"
+	"tailscale.com/tailcfg"
+)
+
+var portmapTypes = []string{"", "pmp", "pcp", "upnp"}
+
+// NAT runs the local client's port mapping probe and reports it alongside the endpoints and home DERP in Status.Self,
+// and whether the last netcheck saw the NAT map per destination.
+func (t *TSymbioteAdapterServer) NAT(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &types.NATInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode nat input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	opts := &local.DebugPortmapOpts{Type: input.Type}
+	if input.Duration != "" {
+		opts.Duration, err = time.ParseDuration(input.Duration)
+		if err != nil || opts.Duration <= 0 || opts.Duration > consts.MaxPortmapDuration {
+			r.Log.Errorw("invalid portmap duration", "duration", input.Duration, "error", err)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+	}
+	if !slices.Contains(portmapTypes, opts.Type) {
+		r.Log.Errorw("invalid portmap type", "type", opts.Type)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	status, err := t.Host().Status(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get status", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	report := types.NATReport{Endpoints: []string{}}
+	if status.Self != nil {
+		report.HomeDERP = status.Self.Relay
+		report.Endpoints = append(report.Endpoints, status.Self.Addrs...)
+	}
+	report.AnalyzeEndpoints()
+
+	report.VaryingPorts, err = t.mappingVariesByDestIP(r.Context())
+	if err != nil {
+		r.Log.Infow("failed to read netcheck result from netmap", "error", err)
+	}
+
+	logs, err := t.Host().DebugPortmap(r.Context(), opts)
+	if err != nil {
+		r.Log.Errorw("failed to run portmap probe", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+	defer logs.Close()
+
+	data, err := io.ReadAll(logs)
+	if err != nil {
+		r.Log.Errorw("failed to read portmap probe", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+	report.Portmap = types.ParsePortmapLog(string(data))
+
+	t.WriteJson(w, r, report)
+}
+
+// mappingVariesByDestIP reads whether the last netcheck saw our NAT mapping change with the destination.
+func (t *TSymbioteAdapterServer) mappingVariesByDestIP(ctx context.Context) (bool, error) {
+	netInfo, err := t.selfNetInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	varies, _ := netInfo.MappingVariesByDestIP().Get()
+	return varies, nil
+}
+
+// selfNetInfo reads the last netcheck result tailscaled sent to control, it comes back on our own node in the netmap.
+func (t *TSymbioteAdapterServer) selfNetInfo(ctx context.Context) (tailcfg.NetInfoView, error) {
+	watcher, err := t.Host().WatchIPNBus(ctx, ipn.NotifyInitialNetMap)
+	if err != nil {
+		return tailcfg.NetInfoView{}, err
+	}
+	defer watcher.Close()
+
+	notify, err := watcher.Next()
+	if err != nil {
+		return tailcfg.NetInfoView{}, err
+	}
+	if notify.NetMap == nil || !notify.NetMap.SelfNode.Valid() {
+		return tailcfg.NetInfoView{}, errors.New("no netmap, is the node logged in?")
+	}
+
+	hostinfo := notify.NetMap.SelfNode.Hostinfo()
+	if !hostinfo.Valid() || !hostinfo.NetInfo().Valid() {
+		return tailcfg.NetInfoView{}, errors.New("no netcheck result yet")
+	}
+	return hostinfo.NetInfo(), nil
+}
@@ -0,0 +1,67 @@
+package tsymbioteadapter
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/ipn"
+	"tailscale.com/tailcfg"
+)
+
+// PacketFilter returns the packet filter rules this node enforces along with the node capabilities from its netmap.
+func (t *TSymbioteAdapterServer) PacketFilter(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	rules, err := t.Host().DebugPacketFilterRules(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get packet filter rules", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	watcher, err := t.Host().WatchIPNBus(r.Context(), ipn.NotifyInitialNetMap|ipn.NotifyInitialPrefs)
+	if err != nil {
+		r.Log.Errorw("failed to watch ipn bus", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+	defer watcher.Close()
+
+	// The initial netmap and prefs are sent together as the first notification.
+	notify, err := watcher.Next()
+	if err == nil && notify.NetMap == nil {
+		err = errors.New("no netmap, is the node logged in?")
+	}
+	if err != nil {
+		r.Log.Errorw("failed to read initial netmap", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	netMap := notify.NetMap
+	report := types.PacketFilterReport{
+		Rules: rules,
+		Peers: []types.PeerCapabilities{},
+	}
+	if report.Rules == nil {
+		report.Rules = []tailcfg.FilterRule{}
+	}
+	if notify.Prefs != nil {
+		report.ShieldsUp = notify.Prefs.ShieldsUp()
+	}
+	if netMap.SelfNode.Valid() {
+		report.Addresses = netMap.SelfNode.Addresses().AsSlice()
+		report.Capabilities = netMap.SelfNode.CapMap().AsMap()
+	}
+
+	for _, peer := range netMap.Peers {
+		report.Peers = append(report.Peers, types.PeerCapabilities{
+			Name:         peer.Name(),
+			HostName:     peer.Hostinfo().Hostname(),
+			Addresses:    peer.Addresses().AsSlice(),
+			Capabilities: peer.CapMap().AsMap(),
+		})
+	}
+
+	t.WriteJson(w, r, report)
+}
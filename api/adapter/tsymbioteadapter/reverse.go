@@ -0,0 +1,90 @@
+package tsymbioteadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/yamux"
+	"github.com/spf13/viper"
+)
+
+// reverseConnect keeps a tunnel open to the WebUI and serves the adapter API over it.
+// This is for hosts whose grants only allow traffic toward the WebUI, the regular listener keeps running either way.
+func (t *TSymbioteAdapterServer) reverseConnect(webui string) tsymbiote.WebsocketFunc {
+	return func(shutdownCtx context.Context) {
+		attempt := 0
+		for {
+			connected, err := t.serveTunnel(shutdownCtx, webui)
+			if shutdownCtx.Err() != nil || errors.Is(err, http.ErrServerClosed) {
+				return
+			}
+
+			// A tunnel that came up starts the backoff over, only repeated dial failures slow us down.
+			if connected {
+				attempt = 0
+			}
+			attempt++
+			delay := utils.Backoff(attempt, consts.WSReconnectBaseDelay, consts.WSReconnectMaxDelay)
+			t.Log.Infow("reverse tunnel closed, reconnecting", "webui", webui, "attempt", attempt, "retryIn", delay, "error", err)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-shutdownCtx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// serveTunnel dials the WebUI and serves requests until the tunnel drops.
+func (t *TSymbioteAdapterServer) serveTunnel(ctx context.Context, webui string) (bool, error) {
+	target := url.URL{Scheme: "ws", Host: webui, Path: paths.Tunnel.WebUI()}
+	wsDialer := &websocket.Dialer{
+		HandshakeTimeout: consts.OutgoingRequestTimeout,
+		NetDialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			if viper.GetBool("dev") {
+				return (&net.Dialer{}).DialContext(ctx, network, address)
+			}
+			return t.TSNet().Dial(ctx, network, address)
+		},
+	}
+
+	// Only used in dev mode, otherwise the WebUI names us from WhoIs.
+	headers := http.Header{}
+	headers.Set("ts-adapter", t.TSNet().Hostname)
+
+	ws, _, err := wsDialer.DialContext(ctx, target.String(), headers)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial webui: %w", err)
+	}
+
+	session, err := yamux.Server(tsymbiote.NewWebsocketConn(ws), nil)
+	if err != nil {
+		ws.Close()
+		return false, fmt.Errorf("failed to start tunnel session: %w", err)
+	}
+	t.Log.Infow("reverse tunnel connected", "webui", webui)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-session.CloseChan():
+		}
+		session.Close()
+	}()
+
+	// The session is a net.Listener, every stream the WebUI opens is served like an inbound connection.
+	return true, t.HTTP().Serve(session)
+}
@@ -23,17 +23,27 @@ func (t *TSymbioteAdapterServer) RegisterRoutes() {
 	t.Route().Get().RegisterSimple("/debug/pprof/symbol", pprof.Symbol)
 	t.Route().Get().RegisterSimple("/debug/pprof/trace", pprof.Trace)
 
-	t.Route().Post().Register(paths.Ping.Adapter(), t.Ping)
-	t.Route().Post().Register(paths.Status.Adapter(), t.Status)
-	t.Route().Post().Register(paths.QueryDNS.Adapter(), t.QueryDNS)
-	t.Route().Post().Register(paths.Pprof.Adapter(), t.Pprof)
-	t.Route().Post().Register(paths.Prefs.Adapter(), t.Prefs)
-	t.Route().Post().Register(paths.DriveShares.Adapter(), t.DriveShares)
-	t.Route().Post().Register(paths.DNSConfig.Adapter(), t.DNSConfig)
-	t.Route().Post().Register(paths.ServeConfig.Adapter(), t.ServeConfig)
-	t.Route().Post().Register(paths.AppConnRoutes.Adapter(), t.AppConnRoutes)
-	t.Route().Post().Register(paths.Goroutines.Adapter(), t.Goroutines)
-
-	t.Route().Websocket().Register(paths.Logs.Adapter(), t.Logs)
-	t.Route().Websocket().Register(paths.BusEvents.Adapter(), t.BusEvents)
+	t.Route().Post().Register(paths.Instances.Adapter(), t.Instances)
+
+	// Everything that talks to tailscaled is served at the root and for every instance.
+	t.hostRoute(paths.Ping, (*TSymbioteAdapterServer).Ping)
+	t.hostRoute(paths.Status, (*TSymbioteAdapterServer).Status)
+	t.hostRoute(paths.QueryDNS, (*TSymbioteAdapterServer).QueryDNS)
+	t.hostRoute(paths.Pprof, (*TSymbioteAdapterServer).Pprof)
+	t.hostRoute(paths.Prefs, (*TSymbioteAdapterServer).Prefs)
+	t.hostRoute(paths.DriveShares, (*TSymbioteAdapterServer).DriveShares)
+	t.hostRoute(paths.DNSConfig, (*TSymbioteAdapterServer).DNSConfig)
+	t.hostRoute(paths.ServeConfig, (*TSymbioteAdapterServer).ServeConfig)
+	t.hostRoute(paths.AppConnRoutes, (*TSymbioteAdapterServer).AppConnRoutes)
+	t.hostRoute(paths.Goroutines, (*TSymbioteAdapterServer).Goroutines)
+	t.hostRoute(paths.BugReport, (*TSymbioteAdapterServer).BugReport)
+	t.hostRoute(paths.DERPMap, (*TSymbioteAdapterServer).DERPMap)
+	t.hostRoute(paths.DERPRegions, (*TSymbioteAdapterServer).DERPRegions)
+	t.hostRoute(paths.NAT, (*TSymbioteAdapterServer).NAT)
+	t.hostRoute(paths.PacketFilter, (*TSymbioteAdapterServer).PacketFilter)
+	t.hostRoute(paths.TailnetLock, (*TSymbioteAdapterServer).TailnetLock)
+	t.hostRoute(paths.ServeProbe, (*TSymbioteAdapterServer).ServeProbe)
+
+	t.hostWebsocket(paths.Logs, (*TSymbioteAdapterServer).Logs)
+	t.hostWebsocket(paths.BusEvents, (*TSymbioteAdapterServer).BusEvents)
 }
@@ -0,0 +1,70 @@
+package tsymbioteadapter
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+// serveProbeTimeout is how long a proxy target has to accept a TCP connection.
+const serveProbeTimeout = time.Second * 2
+
+// ServeProbe dials every proxy and TCP forward target in the serve config from this host, where the targets resolve.
+// Only targets from the config are dialed, the adapter won't probe arbitrary addresses.
+// A tailscaled in another network namespace resolves its targets somewhere we can't dial, its probes are marked skipped.
+func (t *TSymbioteAdapterServer) ServeProbe(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	config, err := t.Host().GetServeConfig(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get serveconfig", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	var probes []types.ServeProbeResult
+	seen := map[string]bool{}
+	for _, handler := range types.FlattenServeConfig(config) {
+		address := handler.ProbeAddress()
+		if address == "" || seen[handler.Target] {
+			continue
+		}
+		seen[handler.Target] = true
+		probes = append(probes, types.ServeProbeResult{Target: handler.Target, Address: address})
+	}
+
+	if t.host.Load().otherNetns {
+		for i := range probes {
+			probes[i].Skipped = "tailscaled is in another network namespace than the adapter"
+		}
+		if probes == nil {
+			probes = []types.ServeProbeResult{}
+		}
+		t.WriteJson(w, r, probes)
+		return
+	}
+
+	dialer := &net.Dialer{Timeout: serveProbeTimeout}
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Go(func() {
+			started := time.Now()
+			conn, err := dialer.DialContext(r.Context(), "tcp", probes[i].Address)
+			if err != nil {
+				probes[i].Error = err.Error()
+				return
+			}
+			probes[i].Latency = time.Since(started)
+			probes[i].Reachable = true
+			conn.Close()
+		})
+	}
+	wg.Wait()
+
+	if probes == nil {
+		probes = []types.ServeProbeResult{}
+	}
+	t.WriteJson(w, r, probes)
+}
@@ -1,12 +1,17 @@
 package tsymbioteadapter
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
+	"os"
 	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/dhouti/tsymbiote/api/adapter/internal"
+	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
-	"github.com/dhouti/tsymbiote/pkg/utils"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"tailscale.com/client/local"
@@ -16,8 +21,20 @@ type TSymbioteAdapterServer struct {
 	*tsymbiote.TSymbioteServer
 	*local.Client
 	*internal.KnownPeers
-	host       *local.Client
+	// host is swapped when discovery loses the root's socket, see rediscoverSockets.
+	host       atomic.Pointer[hostSocket]
 	allowedTag string
+	// relayToken is set when running behind a relay, it replaces WhoIs auth.
+	relayToken string
+	// map[name]*TSymbioteAdapterServer, one per tailscaled when serving several.
+	instances sync.Map
+}
+
+// hostSocket is the tailscaled a server's handlers run against.
+type hostSocket struct {
+	client *local.Client
+	// otherNetns is set when the tailscaled is in another network namespace, see ServeProbe.
+	otherNetns bool
 }
 
 func NewTSymbioteAdapter() tsymbiote.TSymbiote {
@@ -32,20 +49,16 @@ func NewTSymbioteAdapter() tsymbiote.TSymbiote {
 	// In most environments the default LocalClient works, but we sometimes need to swap the Socket path.
 	hostClient := &local.Client{}
 
-	// Handle custom socket paths
-	customSocketPath := viper.GetString("socket")
-	if customSocketPath != "" {
-		hostClient.Socket = customSocketPath
+	// With more than one socket the first is still served at the root, every socket is also served as an instance.
+	instances, err := loadInstances(context.Background(), tsymbiote.Log)
+	if err != nil {
+		tsymbiote.Log.Errorw("failed to find tailscale socket paths", "error", err)
+		return nil
 	}
-
-	// if --socket and --discover-socket prefer discovery
-	if viper.GetBool("discover-socket") {
-		discoveredSocketPath, err := utils.DiscoverSocket()
-		if err != nil {
-			tsymbiote.Log.Errorw("failed to discover tailscale socket path", err)
-			return nil
-		}
-		hostClient.Socket = discoveredSocketPath
+	var otherNetns bool
+	if len(instances) > 0 {
+		hostClient.Socket = instances[0].Socket
+		otherNetns = instances[0].OtherNetns
 	}
 
 	allowTag := viper.GetString("allowed-tag")
@@ -54,20 +67,46 @@ func NewTSymbioteAdapter() tsymbiote.TSymbiote {
 		return nil
 	}
 
+	// Behind a relay there is no tsnet node to WhoIs against, the relay proves itself with a shared token instead.
+	var relayToken string
+	if viper.GetBool("behind-relay") {
+		token, ok := os.LookupEnv(consts.RelayTokenEnv)
+		if !ok || token == "" {
+			tsymbiote.Log.Errorf("%s must be set when running behind a relay", consts.RelayTokenEnv)
+			return nil
+		}
+		if viper.GetString("reverse-connect") != "" {
+			tsymbiote.Log.Error("reverse-connect can't be used behind a relay")
+			return nil
+		}
+		relayToken = token
+	}
+
 	adapter := &TSymbioteAdapterServer{
 		TSymbioteServer: tsymbiote,
-		host:            hostClient,
 		allowedTag:      allowTag,
+		relayToken:      relayToken,
 		KnownPeers:      &internal.KnownPeers{},
 	}
+	adapter.host.Store(&hostSocket{client: hostClient, otherNetns: otherNetns})
+
+	adapter.setInstances(instances)
 
 	// Setup our routes
 	adapter.RegisterRoutes()
+
+	if viper.GetBool("discover-socket") {
+		adapter.RunWSFunc(adapter.rediscoverSockets)
+	}
+
+	if webui := viper.GetString("reverse-connect"); webui != "" {
+		adapter.RunWSFunc(adapter.reverseConnect(webui))
+	}
 	return adapter
 }
 
 func (t *TSymbioteAdapterServer) Host() *local.Client {
-	return t.host
+	return t.host.Load().client
 }
 
 func (t *TSymbioteAdapterServer) Route() *tsymbiote.MiddlewareChain {
@@ -79,7 +118,13 @@ func (t *TSymbioteAdapterServer) Route() *tsymbiote.MiddlewareChain {
 		Mux: t.Mux,
 	}
 
-	if !viper.GetBool("dev") {
+	if viper.GetBool("dev") {
+		return middleware
+	}
+
+	if t.relayToken != "" {
+		middleware.Add(t.relayAuth)
+	} else {
 		middleware.Add(t.adapterAuth)
 	}
 	return middleware
@@ -104,3 +149,18 @@ func (t *TSymbioteAdapterServer) adapterAuth(next tsymbiote.HandlerFunc) tsymbio
 		next(w, r)
 	}
 }
+
+// relayAuth only lets requests through that carry the token shared with the relay.
+func (t *TSymbioteAdapterServer) relayAuth(next tsymbiote.HandlerFunc) tsymbiote.HandlerFunc {
+	return func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+		token := r.Header.Get(consts.RelayTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.relayToken)) != 1 {
+			r.Log.Errorw("request rejected, invalid relay token", "addr", r.RemoteAddr)
+			r.SetStatusCode(w, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
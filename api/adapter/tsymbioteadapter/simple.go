@@ -126,3 +126,15 @@ func (t *TSymbioteAdapterServer) Status(w http.ResponseWriter, r *tsymbiote.HTTP
 
 	t.WriteUnstructuredJSON(w, r, resp)
 }
+
+func (t *TSymbioteAdapterServer) TailnetLock(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+	resp, err := t.Host().NetworkLockStatus(r.Context())
+	if err != nil {
+		r.Log.Errorw("failed to get network lock status", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	t.WriteJson(w, r, types.NewTailnetLockStatus(resp))
+}
@@ -0,0 +1,14 @@
+package tsymbioteadapter
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTSymbioteAdapter(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "TSymbiote Adapter Suite")
+}
@@ -0,0 +1,61 @@
+package tsymbioterelay
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// namespaceFile is mounted into every pod with a service account, it's the relay's own namespace.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+type discoverFunc func(ctx context.Context) ([]types.RelayTarget, error)
+
+// kubernetesDiscovery lists pods matching the selector and targets the adapter port on each pod IP.
+// An empty namespace means the relay's own namespace, the service account needs list on pods there.
+func kubernetesDiscovery(namespace string, selector string, adapterPort string) (discoverFunc, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		raw, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return nil, err
+		}
+		namespace = strings.TrimSpace(string(raw))
+	}
+
+	return func(ctx context.Context) ([]types.RelayTarget, error) {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+
+		targets := []types.RelayTarget{}
+		for _, pod := range pods.Items {
+			// Pending and terminating pods would only show up as failed hosts.
+			if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+				continue
+			}
+			targets = append(targets, types.RelayTarget{
+				Name:    pod.Name,
+				Address: net.JoinHostPort(pod.Status.PodIP, adapterPort),
+			})
+		}
+		return targets, nil
+	}, nil
+}
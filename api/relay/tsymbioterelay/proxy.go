@@ -0,0 +1,44 @@
+package tsymbioterelay
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+)
+
+// Relay lists the adapters behind the relay, the WebUI calls each one as <relay>/relay/<name>.
+func (t *TSymbioteRelayServer) Relay(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	t.WriteJson(w, r, t.getTargets())
+}
+
+// Proxy forwards /relay/<name>/<path> to <path> on the named adapter, websocket upgrades included.
+// trace-id and ts-username pass through untouched, the relay token is added on the way out.
+func (t *TSymbioteRelayServer) Proxy(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	name := r.PathValue("target")
+	target, ok := t.getTarget(name)
+	if !ok {
+		r.Log.Errorw("unknown relay target", "target", name)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(req *httputil.ProxyRequest) {
+			req.Out.URL.Scheme = "http"
+			req.Out.URL.Host = target.Address
+			req.Out.URL.Path = strings.TrimPrefix(req.In.URL.Path, consts.RelayPathPrefix+name)
+			req.Out.URL.RawPath = ""
+			req.Out.Host = target.Address
+			req.Out.Header.Set(consts.RelayTokenHeader, t.token)
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			r.Log.Errorw("failed to call relay target", "target", name, "address", target.Address, "error", err)
+			r.SetStatusCode(w, http.StatusInternalServerError)
+		},
+	}
+
+	proxy.ServeHTTP(w, r.Request)
+}
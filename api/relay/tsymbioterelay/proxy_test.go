@@ -0,0 +1,119 @@
+package tsymbioterelay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("Proxy", func() {
+	var adapter *httptest.Server
+	var relay *httptest.Server
+
+	BeforeEach(func() {
+		// Dev mode skips WhoIs, there is no tailnet here.
+		viper.Set("dev", true)
+		DeferCleanup(viper.Set, "dev", false)
+
+		upgrader := websocket.Upgrader{}
+		adapter = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if websocket.IsWebSocketUpgrade(r) {
+				conn, err := upgrader.Upgrade(w, r, nil)
+				Expect(err).NotTo(HaveOccurred())
+				defer conn.Close()
+				conn.WriteMessage(websocket.TextMessage, []byte(r.URL.Path))
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]string{
+				"path":  r.URL.Path,
+				"token": r.Header.Get(consts.RelayTokenHeader),
+				"trace": r.Header.Get("trace-id"),
+			})
+		}))
+		DeferCleanup(adapter.Close)
+
+		server := &TSymbioteRelayServer{
+			TSymbioteServer: &tsymbiote.TSymbioteServer{Log: zap.NewNop().Sugar(), Mux: http.NewServeMux()},
+			token:           "secret",
+			targets: map[string]types.RelayTarget{
+				"web-0": {Name: "web-0", Address: adapter.Listener.Addr().String()},
+			},
+		}
+		server.RegisterRoutes()
+
+		relay = httptest.NewServer(server.Mux)
+		DeferCleanup(relay.Close)
+	})
+
+	It("Should strip the relay prefix and add the relay token", func() {
+		req, err := http.NewRequest(http.MethodPost, relay.URL+"/relay/web-0"+paths.Status.Adapter(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set("trace-id", "trace-1")
+		req.Header.Set(consts.RelayTokenHeader, "from-the-caller")
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		seen := map[string]string{}
+		Expect(json.NewDecoder(resp.Body).Decode(&seen)).To(Succeed())
+		Expect(seen).To(Equal(map[string]string{
+			"path":  paths.Status.Adapter(),
+			"token": "secret",
+			"trace": "trace-1",
+		}))
+	})
+
+	It("Should keep instance paths for the adapter to route", func() {
+		resp, err := http.Post(relay.URL+"/relay/web-0/instance/proxy"+paths.Status.Adapter(), "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		seen := map[string]string{}
+		Expect(json.NewDecoder(resp.Body).Decode(&seen)).To(Succeed())
+		Expect(seen["path"]).To(Equal("/instance/proxy" + paths.Status.Adapter()))
+	})
+
+	It("Should pass websocket upgrades through", func() {
+		url := "ws" + strings.TrimPrefix(relay.URL, "http") + "/relay/web-0" + paths.Logs.Adapter()
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		_, message, err := conn.ReadMessage()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(message)).To(Equal(paths.Logs.Adapter()))
+	})
+
+	It("Should 404 unknown targets", func() {
+		resp, err := http.Post(relay.URL+"/relay/web-1"+paths.Status.Adapter(), "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should list targets", func() {
+		resp, err := http.Post(relay.URL+paths.Relay.Adapter(), "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		var targets []types.RelayTarget
+		Expect(json.NewDecoder(resp.Body).Decode(&targets)).To(Succeed())
+		Expect(targets).To(Equal([]types.RelayTarget{{Name: "web-0", Address: adapter.Listener.Addr().String()}}))
+	})
+})
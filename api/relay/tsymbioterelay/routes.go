@@ -0,0 +1,17 @@
+package tsymbioterelay
+
+import (
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+)
+
+// RegisterRoutes is used to define and configure out HTTP routes
+func (t *TSymbioteRelayServer) RegisterRoutes() {
+
+	t.RouteNoAuth().Get().Register("/healthz", t.Healthz)
+
+	t.Route().Post().Register(paths.Relay.Adapter(), t.Relay)
+
+	// Everything else, websockets included, belongs to the adapter behind the relay.
+	t.Route().Register(consts.RelayPathPrefix+"{target}/", t.Proxy)
+}
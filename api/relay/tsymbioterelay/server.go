@@ -0,0 +1,190 @@
+package tsymbioterelay
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// TSymbioteRelayServer joins the tailnet once and fronts every adapter in its cluster.
+// Adapters behind it run with --behind-relay and are reached over the pod network.
+type TSymbioteRelayServer struct {
+	*tsymbiote.TSymbioteServer
+	allowedTag string
+	token      string
+	discover   discoverFunc
+
+	mu sync.RWMutex
+	// map[name]target
+	targets map[string]types.RelayTarget
+}
+
+func NewTSymbioteRelay() tsymbiote.TSymbiote {
+	token, ok := os.LookupEnv(consts.RelayTokenEnv)
+	if !ok || token == "" {
+		log := zap.Must(zap.NewProduction()).Sugar()
+		log.Errorf("%s must be set, adapters behind the relay use it to authenticate requests", consts.RelayTokenEnv)
+		return nil
+	}
+
+	// Don't provide auth key, use env.
+	tsymbiote := tsymbiote.NewTSymbiote(nil)
+	if tsymbiote == nil {
+		log := zap.Must(zap.NewProduction()).Sugar()
+		log.Error("failed to setup TSymbiote")
+		return nil
+	}
+
+	allowTag := viper.GetString("allowed-tag")
+	if allowTag == "" {
+		tsymbiote.Log.Error("allowed-tag must be set, where is the default?")
+		return nil
+	}
+
+	// A static list wins over discovery, it's mostly for clusters the relay can't list pods in.
+	var discover discoverFunc
+	if staticTargets := viper.GetStringSlice("targets"); len(staticTargets) > 0 {
+		targets, err := types.ParseRelayTargets(staticTargets)
+		if err != nil {
+			tsymbiote.Log.Errorw("failed to parse targets", "error", err)
+			return nil
+		}
+		discover = func(context.Context) ([]types.RelayTarget, error) {
+			return targets, nil
+		}
+	} else {
+		selector := viper.GetString("label-selector")
+		if selector == "" {
+			tsymbiote.Log.Error("one of targets or label-selector must be set")
+			return nil
+		}
+
+		var err error
+		discover, err = kubernetesDiscovery(viper.GetString("namespace"), selector, viper.GetString("adapter-port"))
+		if err != nil {
+			tsymbiote.Log.Errorw("failed to setup kubernetes discovery", "error", err)
+			return nil
+		}
+	}
+
+	relay := &TSymbioteRelayServer{
+		TSymbioteServer: tsymbiote,
+		allowedTag:      allowTag,
+		token:           token,
+		discover:        discover,
+		targets:         map[string]types.RelayTarget{},
+	}
+
+	// Setup our routes
+	relay.RegisterRoutes()
+
+	relay.RunWSFunc(relay.discoveryLoop)
+	return relay
+}
+
+func (t *TSymbioteRelayServer) Route() *tsymbiote.MiddlewareChain {
+	middleware := &tsymbiote.MiddlewareChain{
+		TSymbiote: t,
+		Middleware: []tsymbiote.Middleware{
+			t.RequestLogger,
+		},
+		Mux: t.Mux,
+	}
+
+	if !viper.GetBool("dev") {
+		middleware.Add(t.relayAuth)
+	}
+	return middleware
+}
+
+// relayAuth is the same check adapters do, only the tsymbiote-webui gets through to the adapters behind us.
+func (t *TSymbioteRelayServer) relayAuth(next tsymbiote.HandlerFunc) tsymbiote.HandlerFunc {
+	return func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+		resp, err := t.Local().WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			r.Log.Errorw("failed to get whois", "error", err)
+			r.SetStatusCode(w, http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(resp.Node.Tags, t.allowedTag) {
+			r.SetStatusCode(w, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// discoveryLoop refreshes the targets right away and then on every interval until shutdown.
+func (t *TSymbioteRelayServer) discoveryLoop(shutdownCtx context.Context) {
+	ticker := time.NewTicker(consts.RelayDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		t.refreshTargets(shutdownCtx)
+
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshTargets keeps the previous targets when discovery fails, a flaky API server shouldn't empty the fleet.
+func (t *TSymbioteRelayServer) refreshTargets(ctx context.Context) {
+	discoverCtx, discoverCancel := context.WithTimeout(ctx, consts.OutgoingRequestTimeout)
+	defer discoverCancel()
+
+	targets, err := t.discover(discoverCtx)
+	if err != nil {
+		t.Log.Errorw("failed to discover adapters", "error", err)
+		return
+	}
+
+	current := make(map[string]types.RelayTarget, len(targets))
+	for _, target := range targets {
+		current[target.Name] = target
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(current) != len(t.targets) {
+		t.Log.Infow("relay targets changed", "targets", len(current))
+	}
+	t.targets = current
+}
+
+func (t *TSymbioteRelayServer) getTarget(name string) (types.RelayTarget, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	target, ok := t.targets[name]
+	return target, ok
+}
+
+// getTargets returns every target sorted by name.
+func (t *TSymbioteRelayServer) getTargets() []types.RelayTarget {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	targets := make([]types.RelayTarget, 0, len(t.targets))
+	for _, target := range t.targets {
+		targets = append(targets, target)
+	}
+	slices.SortFunc(targets, func(a, b types.RelayTarget) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return targets
+}
@@ -0,0 +1,14 @@
+package tsymbioterelay
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTSymbioteRelay(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "TSymbiote Relay Suite")
+}
@@ -0,0 +1,10 @@
+package consts
+
+import "time"
+
+const (
+	// InstancePathPrefix is where an adapter serves each tailscaled when it has more than one, IE: /instance/<name>/Status.
+	InstancePathPrefix = "/instance/"
+	// SocketDiscoveryInterval is how often a discovering adapter looks for tailscaled sockets coming and going.
+	SocketDiscoveryInterval = time.Second * 30
+)
@@ -20,6 +20,27 @@ const (
 	Hosts
 	PeerMap
 	BusEvents
+	Recordings
+	Replay
+	Stream
+	Jobs
+	Runbooks
+	Assertions
+	Checks
+	Inventory
+	RouteConflicts
+	Bundle
+	BugReport
+	DERPMap
+	DERPRegions
+	NAT
+	PacketFilter
+	TailnetLock
+	ServeProbe
+	ServeFleet
+	Tunnel
+	Relay
+	Instances
 	End // Just a marker
 )
 
@@ -22,12 +22,33 @@ func _() {
 	_ = x[Hosts-11]
 	_ = x[PeerMap-12]
 	_ = x[BusEvents-13]
-	_ = x[End-14]
+	_ = x[Recordings-14]
+	_ = x[Replay-15]
+	_ = x[Stream-16]
+	_ = x[Jobs-17]
+	_ = x[Runbooks-18]
+	_ = x[Assertions-19]
+	_ = x[Checks-20]
+	_ = x[Inventory-21]
+	_ = x[RouteConflicts-22]
+	_ = x[Bundle-23]
+	_ = x[BugReport-24]
+	_ = x[DERPMap-25]
+	_ = x[DERPRegions-26]
+	_ = x[NAT-27]
+	_ = x[PacketFilter-28]
+	_ = x[TailnetLock-29]
+	_ = x[ServeProbe-30]
+	_ = x[ServeFleet-31]
+	_ = x[Tunnel-32]
+	_ = x[Relay-33]
+	_ = x[Instances-34]
+	_ = x[End-35]
 }
 
-const _KnownPath_name = "StatusQueryDNSPingPprofPrefsLogsDriveSharesDNSConfigServeConfigAppConnRoutesGoroutinesHostsPeerMapBusEventsEnd"
+const _KnownPath_name = "StatusQueryDNSPingPprofPrefsLogsDriveSharesDNSConfigServeConfigAppConnRoutesGoroutinesHostsPeerMapBusEventsRecordingsReplayStreamJobsRunbooksAssertionsChecksInventoryRouteConflictsBundleBugReportDERPMapDERPRegionsNATPacketFilterTailnetLockServeProbeServeFleetTunnelRelayInstancesEnd"
 
-var _KnownPath_index = [...]uint8{0, 6, 14, 18, 23, 28, 32, 43, 52, 63, 76, 86, 91, 98, 107, 110}
+var _KnownPath_index = [...]uint16{0, 6, 14, 18, 23, 28, 32, 43, 52, 63, 76, 86, 91, 98, 107, 117, 123, 129, 133, 141, 151, 157, 166, 180, 186, 195, 202, 213, 216, 228, 239, 249, 259, 265, 270, 279, 282}
 
 func (i KnownPath) String() string {
 	idx := int(i) - 0
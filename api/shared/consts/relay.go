@@ -0,0 +1,14 @@
+package consts
+
+import "time"
+
+const (
+	// RelayTokenEnv holds the secret shared by a relay and the adapters behind it.
+	RelayTokenEnv = "TSYMBIOTE_RELAY_TOKEN"
+	// RelayTokenHeader carries the shared secret on every relayed request.
+	RelayTokenHeader = "tsymbiote-relay-token"
+	// RelayPathPrefix is where a relay serves the API of each adapter behind it, IE: /relay/<target>/Status.
+	RelayPathPrefix = "/relay/"
+	// RelayDiscoveryInterval is how often a relay looks for adapters in its cluster.
+	RelayDiscoveryInterval = time.Second * 30
+)
@@ -10,4 +10,15 @@ const (
 	PingPongTimeout           = PingPongInterval + WSWriteTimeout
 	ServerDrainPeriod         = PingPongTimeout
 	OutgoingRequestTimeout    = time.Second * 5
+	WSReconnectBaseDelay      = time.Millisecond * 500
+	WSReconnectMaxDelay       = time.Second * 30
+	WSReconnectMaxAttempts    = 10
+	KnownHostsRefreshTTL      = time.Second * 2
+	PeerMapRequestTimeout     = time.Second * 1
+	RetryBaseDelay            = time.Millisecond * 250
+	RetryMaxDelay             = time.Second * 5
+	MaxRecordingUploadSize    = 64 << 20
+	MaxRecordingLineSize      = 1 << 20
+	MaxRunbookSize            = 1 << 20
+	MaxPortmapDuration        = time.Second * 30
 )
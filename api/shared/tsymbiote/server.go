@@ -53,21 +53,27 @@ func NewTSymbiote(authKey *tailscale.Key) *TSymbioteServer {
 		log.Info("dev mode enabled, authentication is disabled.")
 	}
 
-	tsnet := getTSNetServer(authKey)
-	if tsnet == nil {
-		log.Error("failed to start tsnet server")
-		return nil
+	// Adapters behind a relay are only reached over the pod network, they never join the tailnet.
+	var tsnet *tsnet.Server
+	var localClient *local.Client
+	if !viper.GetBool("behind-relay") {
+		tsnet = getTSNetServer(authKey)
+		if tsnet == nil {
+			log.Error("failed to start tsnet server")
+			return nil
+		}
+
+		// Fetch the base localClient
+		var err error
+		localClient, err = tsnet.LocalClient()
+		if err != nil {
+			log.Errorw("failed to setup local client", "error", err)
+			return nil
+		}
 	}
 
 	mux := http.NewServeMux()
 
-	// Fetch the base localClient
-	localClient, err := tsnet.LocalClient()
-	if err != nil {
-		log.Errorw("failed to setup local client", "error", err)
-		return nil
-	}
-
 	httpServer := &http.Server{
 		Addr:    fmt.Sprintf(":%s", viper.GetString("port")),
 		Handler: mux,
@@ -122,7 +128,7 @@ func (t *TSymbioteServer) Local() *local.Client {
 // ListenAndServe starts the webserver and handles graceful termination.
 func (t *TSymbioteServer) ListenAndServe() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	if viper.GetBool("dev") {
+	if viper.GetBool("dev") || t.TSNet() == nil {
 		go func() {
 			t.Log.Infof("Starting listener on http://localhost:%s", viper.GetString("port"))
 			err := t.HTTP().ListenAndServe()
@@ -170,7 +176,7 @@ func (t *TSymbioteServer) ListenAndServe() {
 	}
 
 	// Logout if flag is set
-	if viper.GetBool("logout") {
+	if viper.GetBool("logout") && t.Local() != nil {
 		t.Log.Info("Attempting to log out out of tailscale")
 		err = t.Local().Logout(gracefulctx)
 		if err != nil {
@@ -182,7 +188,9 @@ func (t *TSymbioteServer) ListenAndServe() {
 
 	t.Log.Sync()
 	t.HTTP().Close()
-	t.TSNet().Close()
+	if t.TSNet() != nil {
+		t.TSNet().Close()
+	}
 	shutdownRelease()
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
 // WebsocketMessage is a convenience type for crafting websocket payloads
@@ -16,6 +17,32 @@ type WebsocketMessage struct {
 type WebsocketHostMessage struct {
 	Host    string
 	Message []byte
+	// Status is only set when the message reports on the adapter connection instead of carrying data.
+	Status *WebsocketStatus `json:",omitempty"`
+}
+
+// Connection states reported in WebsocketStatus.
+const (
+	WebsocketStateDisconnected = "disconnected"
+	WebsocketStateReconnecting = "reconnecting"
+	WebsocketStateReconnected  = "reconnected"
+	WebsocketStateClosed       = "closed"
+)
+
+// WebsocketStatus describes the state of an adapter connection behind an aggregated websocket.
+type WebsocketStatus struct {
+	State   string        `json:"state"`
+	Attempt int           `json:"attempt,omitempty"`
+	RetryIn time.Duration `json:"retryIn,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// WebsocketRecord is a single frame of a recorded websocket session.
+// Offset is relative to the start of the recording, Message keeps the WebsocketHostMessage framing sent to the client.
+type WebsocketRecord struct {
+	Offset  time.Duration `json:"offset"`
+	Type    int           `json:"type"`
+	Message []byte        `json:"message"`
 }
 
 // WebsocketManager is less of a manager more of a context injector and graceful shutdown wait.
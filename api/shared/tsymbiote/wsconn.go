@@ -0,0 +1,94 @@
+package tsymbiote
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebsocketConn turns a websocket into a byte stream so it can carry a multiplexed session.
+// Writes are sent as binary messages, reads continue across message boundaries.
+type WebsocketConn struct {
+	ws *websocket.Conn
+
+	readMu  sync.Mutex
+	reader  io.Reader
+	writeMu sync.Mutex
+}
+
+var _ net.Conn = &WebsocketConn{}
+
+func NewWebsocketConn(ws *websocket.Conn) *WebsocketConn {
+	return &WebsocketConn{ws: ws}
+}
+
+func (c *WebsocketConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for {
+		if c.reader == nil {
+			messageType, reader, err := c.ws.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			c.reader = reader
+		}
+
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *WebsocketConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	err := c.ws.WriteMessage(websocket.BinaryMessage, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *WebsocketConn) Close() error {
+	return c.ws.Close()
+}
+
+func (c *WebsocketConn) LocalAddr() net.Addr {
+	return c.ws.LocalAddr()
+}
+
+// RemoteAddr is the far end of the websocket, WhoIs on it identifies the peer for every multiplexed stream.
+func (c *WebsocketConn) RemoteAddr() net.Addr {
+	return c.ws.RemoteAddr()
+}
+
+func (c *WebsocketConn) SetDeadline(t time.Time) error {
+	err := c.ws.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	return c.ws.SetWriteDeadline(t)
+}
+
+func (c *WebsocketConn) SetReadDeadline(t time.Time) error {
+	return c.ws.SetReadDeadline(t)
+}
+
+func (c *WebsocketConn) SetWriteDeadline(t time.Time) error {
+	return c.ws.SetWriteDeadline(t)
+}
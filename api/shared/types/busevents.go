@@ -0,0 +1,97 @@
+package types
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Query parameters accepted by the BusEvents websocket, all are comma separated.
+const (
+	BusEventsIncludeParam   = "include"
+	BusEventsExcludeParam   = "exclude"
+	BusEventsPublisherParam = "publisher"
+)
+
+// BusEvent is the stable envelope sent to clients for every event bus message.
+// Payload is left as whatever tailscaled serialized so clients don't depend on the Go type.
+type BusEvent struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Count       int       `json:"count"`
+	Type        string    `json:"type"`
+	Publisher   string    `json:"publisher"`
+	Subscribers []string  `json:"subscribers,omitempty"`
+	Payload     any       `json:"payload,omitempty"`
+}
+
+// BusEventFilter matches event types and publishers using case insensitive substrings.
+// IE: include=portmapper,netmon matches "portmapper.Mapping" and "*netmon.ChangeDelta".
+type BusEventFilter struct {
+	Include    []string `json:"include,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	Publishers []string `json:"publishers,omitempty"`
+}
+
+// NewBusEventFilter builds a filter from websocket query parameters.
+func NewBusEventFilter(params url.Values) *BusEventFilter {
+	return &BusEventFilter{
+		Include:    splitParam(params.Get(BusEventsIncludeParam)),
+		Exclude:    splitParam(params.Get(BusEventsExcludeParam)),
+		Publishers: splitParam(params.Get(BusEventsPublisherParam)),
+	}
+}
+
+// Values converts the filter back into query parameters, used when forwarding to adapters.
+func (f *BusEventFilter) Values() url.Values {
+	params := url.Values{}
+	if len(f.Include) > 0 {
+		params.Set(BusEventsIncludeParam, strings.Join(f.Include, ","))
+	}
+	if len(f.Exclude) > 0 {
+		params.Set(BusEventsExcludeParam, strings.Join(f.Exclude, ","))
+	}
+	if len(f.Publishers) > 0 {
+		params.Set(BusEventsPublisherParam, strings.Join(f.Publishers, ","))
+	}
+	return params
+}
+
+// Match reports whether an event with the given type and publisher should be sent.
+// Exclusions win over inclusions, empty lists match everything.
+func (f *BusEventFilter) Match(eventType string, publisher string) bool {
+	if containsAny(eventType, f.Exclude) {
+		return false
+	}
+
+	if len(f.Include) > 0 && !containsAny(eventType, f.Include) {
+		return false
+	}
+
+	if len(f.Publishers) > 0 && !containsAny(publisher, f.Publishers) {
+		return false
+	}
+
+	return true
+}
+
+func containsAny(value string, filters []string) bool {
+	value = strings.ToLower(value)
+	for _, filter := range filters {
+		if strings.Contains(value, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitParam splits a csv query param, dropping empty values and lowercasing for matching.
+func splitParam(raw string) []string {
+	var out []string
+	for value := range strings.SplitSeq(raw, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
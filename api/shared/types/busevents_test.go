@@ -0,0 +1,51 @@
+package types
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BusEventFilter", func() {
+
+	Context("When no filters are provided", func() {
+		It("Should match everything", func() {
+			filter := NewBusEventFilter(url.Values{})
+			Expect(filter.Match("portmapper.Mapping", "portmapper")).To(BeTrue())
+			Expect(filter.Match("*netmon.ChangeDelta", "netmon")).To(BeTrue())
+		})
+	})
+
+	Context("When filters are provided as query params", func() {
+		var filter *BusEventFilter
+
+		BeforeEach(func() {
+			filter = NewBusEventFilter(url.Values{
+				BusEventsIncludeParam:   []string{"PortMapper, netmon"},
+				BusEventsExcludeParam:   []string{"ChangeDelta"},
+				BusEventsPublisherParam: []string{"magicsock,portmapper"},
+			})
+		})
+
+		It("Should match includes case insensitively", func() {
+			Expect(filter.Match("portmapper.Mapping", "portmapper")).To(BeTrue())
+		})
+
+		It("Should prefer exclusions over inclusions", func() {
+			Expect(filter.Match("*netmon.ChangeDelta", "magicsock")).To(BeFalse())
+		})
+
+		It("Should drop types that aren't included", func() {
+			Expect(filter.Match("controlclient.Status", "magicsock")).To(BeFalse())
+		})
+
+		It("Should drop publishers that aren't included", func() {
+			Expect(filter.Match("portmapper.Mapping", "ipnlocal")).To(BeFalse())
+		})
+
+		It("Should round trip through query params", func() {
+			Expect(NewBusEventFilter(filter.Values())).To(Equal(filter))
+		})
+	})
+})
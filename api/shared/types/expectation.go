@@ -0,0 +1,12 @@
+package types
+
+// ExpectationResult is the outcome of an expectation for a single host result.
+type ExpectationResult struct {
+	Host string `json:"host"`
+	// Target is set for results with more than one entry per host, IE: ping.
+	Target string `json:"target,omitempty"`
+	Passed bool   `json:"passed"`
+	// Value is the offending value when the expectation fails.
+	Value any    `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+)
+
+// HostInstance is one of several tailscaled an adapter serves, the WebUI calls it as <adapter>/instance/<name>.
+type HostInstance struct {
+	Name   string `json:"name"`
+	Socket string `json:"socket"`
+	// OtherNetns is set for discovered tailscaled outside the adapter's network namespace, their localhost isn't ours.
+	OtherNetns bool `json:"otherNetns,omitempty"`
+}
+
+// ParseHostInstances reads a socket list, entries are name=path or just path.
+// Without a name the instance is named by its position in the list.
+func ParseHostInstances(entries []string) ([]HostInstance, error) {
+	parsed, err := parseNamedEntries("socket", entries, func(socket string) error {
+		if socket == "" {
+			return errors.New("missing path")
+		}
+		return nil
+	}, func(_ string, index int) string {
+		return strconv.Itoa(index)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]HostInstance, 0, len(parsed))
+	for _, entry := range parsed {
+		instances = append(instances, HostInstance{Name: entry.Name, Socket: entry.Value})
+	}
+	return instances, nil
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HostInstances", func() {
+	It("Should name instances by position unless a name is given", func() {
+		instances, err := ParseHostInstances([]string{"/run/a/tailscaled.sock", "proxy=/run/b/tailscaled.sock", "/run/c/tailscaled.sock"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instances).To(Equal([]HostInstance{
+			{Name: "0", Socket: "/run/a/tailscaled.sock"},
+			{Name: "proxy", Socket: "/run/b/tailscaled.sock"},
+			{Name: "2", Socket: "/run/c/tailscaled.sock"},
+		}))
+	})
+
+	It("Should reject sockets without a path", func() {
+		_, err := ParseHostInstances([]string{"a="})
+		Expect(err).To(HaveOccurred())
+	})
+})
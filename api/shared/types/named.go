@@ -0,0 +1,50 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// namedEntry is one name=value entry of a flag list.
+type namedEntry struct {
+	Name  string
+	Value string
+}
+
+// parseNamedEntries reads flag entries of name=value or just value, blank entries are skipped.
+// validate checks every value and unnamed names an entry given as just a value, from its value and position.
+// Names end up as a path segment so they must be unique and can't hold /, ? or #.
+func parseNamedEntries(kind string, entries []string, validate func(value string) error, unnamed func(value string, index int) string) ([]namedEntry, error) {
+	parsed := []namedEntry{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, named := strings.Cut(entry, "=")
+		if !named {
+			value = entry
+		}
+
+		err := validate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", kind, entry, err)
+		}
+		if !named {
+			name = unnamed(value, len(parsed))
+		}
+
+		if name == "" || strings.ContainsAny(name, "/?#") {
+			return nil, fmt.Errorf("invalid %s name %q", kind, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate %s name %q", kind, name)
+		}
+		seen[name] = true
+
+		parsed = append(parsed, namedEntry{Name: name, Value: value})
+	}
+	return parsed, nil
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NamedEntries", func() {
+	parse := func(entries ...string) ([]namedEntry, error) {
+		return parseNamedEntries("entry", entries, func(value string) error {
+			if value == "bad" {
+				return errors.New("bad value")
+			}
+			return nil
+		}, func(value string, _ int) string {
+			return strings.ToUpper(value)
+		})
+	}
+
+	It("Should trim entries, skip blank ones and name unnamed entries", func() {
+		parsed, err := parse(" a=one ", "", "two", "b=")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed).To(Equal([]namedEntry{
+			{Name: "a", Value: "one"},
+			{Name: "TWO", Value: "two"},
+			{Name: "b", Value: ""},
+		}))
+	})
+
+	It("Should reject invalid values", func() {
+		_, err := parse("a=bad")
+		Expect(err).To(MatchError(ContainSubstring(`invalid entry "a=bad"`)))
+	})
+
+	It("Should reject names that can't be a path segment", func() {
+		for _, entry := range []string{"a/b=one", "a?b=one", "a#b=one", "=one"} {
+			_, err := parse(entry)
+			Expect(err).To(HaveOccurred(), entry)
+		}
+	})
+
+	It("Should reject duplicate names, given or not", func() {
+		_, err := parse("a=one", "a=two")
+		Expect(err).To(MatchError(ContainSubstring("duplicate")))
+
+		_, err = parse("ONE=x", "one")
+		Expect(err).To(MatchError(ContainSubstring("duplicate")))
+	})
+})
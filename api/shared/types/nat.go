@@ -0,0 +1,121 @@
+package types
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+type NATInput struct {
+	RequestOptions
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// Type limits the port mapping probe to pmp, pcp or upnp, every type is tried when empty.
+	Type string `json:"type,omitempty"`
+	// Duration to wait for a mapping, IE: 5s
+	Duration string `json:"duration,omitempty"`
+}
+
+// PortmapResult is the parsed output of the local client's DebugPortmap.
+type PortmapResult struct {
+	Gateway string `json:"gateway,omitempty"`
+	Self    string `json:"self,omitempty"`
+	PCP     bool   `json:"pcp"`
+	PMP     bool   `json:"pmp"`
+	UPnP    bool   `json:"upnp"`
+	// Mapping is the external address the router mapped for us.
+	Mapping string   `json:"mapping,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	Log     []string `json:"log"`
+}
+
+// NATReport combines port mapping with the endpoints tailscaled discovered for itself.
+type NATReport struct {
+	Host     string        `json:"host,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Portmap  PortmapResult `json:"portmap"`
+	HomeDERP string        `json:"homeDerp,omitempty"`
+	// Endpoints are Status.Self.Addrs, the addresses peers try for a direct connection.
+	Endpoints []string `json:"endpoints"`
+	PublicIPs []string `json:"publicIps"`
+	// VaryingPorts is netcheck's MappingVariesByDestIP, the NAT maps per destination which makes direct connections hard.
+	// Several ports on one public IP don't mean this, the port mapped and STUN endpoints often share an IP.
+	VaryingPorts bool      `json:"varyingPorts"`
+	Attempts     []Attempt `json:"attempts,omitempty"`
+}
+
+// ParsePortmapLog reads the lines DebugPortmap streams back, IE:
+//
+//	gw=192.168.1.1; self=192.168.1.20
+//	Probe: {PCP:false PMP:true UPnP:false}
+//	mapping: 203.0.113.5:41641
+func ParsePortmapLog(log string) PortmapResult {
+	result := PortmapResult{Log: []string{}}
+
+	for line := range strings.Lines(log) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		result.Log = append(result.Log, line)
+
+		switch {
+		case strings.HasPrefix(line, "gw="):
+			gateway, self, _ := strings.Cut(line, "; ")
+			result.Gateway = strings.TrimPrefix(gateway, "gw=")
+			result.Self = strings.TrimPrefix(self, "self=")
+		case strings.HasPrefix(line, "Probe: "):
+			probe := strings.Trim(strings.TrimPrefix(line, "Probe: "), "{}")
+			for field := range strings.FieldsSeq(probe) {
+				name, value, _ := strings.Cut(field, ":")
+				switch name {
+				case "PCP":
+					result.PCP = value == "true"
+				case "PMP":
+					result.PMP = value == "true"
+				case "UPnP":
+					result.UPnP = value == "true"
+				}
+			}
+		case strings.HasPrefix(line, "mapping: "), strings.HasPrefix(line, "cb: mapping: "):
+			_, mapping, _ := strings.Cut(line, "mapping: ")
+			result.Mapping = mapping
+		case strings.HasPrefix(line, "error "), strings.HasPrefix(line, "no gateway"),
+			line == "no portmapping services available", line == "no mapping", line == "cb: no mapping":
+			result.Error = line
+		}
+	}
+
+	// Any mapping wins, "no mapping" is logged before the router has answered.
+	if result.Mapping != "" {
+		result.Error = ""
+	}
+	return result
+}
+
+// AnalyzeEndpoints fills PublicIPs from the report's Endpoints.
+func (n *NATReport) AnalyzeEndpoints() {
+	n.PublicIPs = []string{}
+
+	for _, endpoint := range n.Endpoints {
+		addrPort, err := netip.ParseAddrPort(endpoint)
+		if err != nil {
+			continue
+		}
+
+		addr := addrPort.Addr().Unmap()
+		if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || isCGNAT(addr) {
+			continue
+		}
+
+		if !slices.Contains(n.PublicIPs, addr.String()) {
+			n.PublicIPs = append(n.PublicIPs, addr.String())
+		}
+	}
+}
+
+var cgnatRange = netip.MustParsePrefix("100.64.0.0/10")
+
+func isCGNAT(addr netip.Addr) bool {
+	return cgnatRange.Contains(addr)
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NAT", func() {
+	It("Should parse a successful port mapping", func() {
+		result := ParsePortmapLog("gw=192.168.1.1; self=192.168.1.20\nProbe: {PCP:false PMP:true UPnP:false}\nno mapping\nportmapping changed.\ncb: mapping: 203.0.113.5:41641\n")
+
+		Expect(result.Gateway).To(Equal("192.168.1.1"))
+		Expect(result.Self).To(Equal("192.168.1.20"))
+		Expect(result.PMP).To(BeTrue())
+		Expect(result.PCP).To(BeFalse())
+		Expect(result.Mapping).To(Equal("203.0.113.5:41641"))
+		Expect(result.Error).To(BeEmpty())
+		Expect(result.Log).To(HaveLen(5))
+	})
+
+	It("Should report when no services are available", func() {
+		result := ParsePortmapLog("gw=10.0.0.1; self=10.0.0.5\nProbe: {PCP:false PMP:false UPnP:false}\nno portmapping services available\n")
+		Expect(result.Error).To(Equal("no portmapping services available"))
+		Expect(result.PMP || result.PCP || result.UPnP).To(BeFalse())
+		Expect(result.Mapping).To(BeEmpty())
+	})
+
+	It("Should find public IPs", func() {
+		report := &NATReport{Endpoints: []string{"10.0.0.5:41641", "203.0.113.5:41641", "203.0.113.5:53122", "[2001:db8::1]:41641"}}
+		report.AnalyzeEndpoints()
+		Expect(report.PublicIPs).To(Equal([]string{"203.0.113.5", "2001:db8::1"}))
+		// A port mapped and a STUN endpoint on the same IP is normal, not a NAT that varies by destination.
+		Expect(report.VaryingPorts).To(BeFalse())
+
+		report = &NATReport{Endpoints: []string{"203.0.113.5:41641", "100.100.1.1:41641"}}
+		report.AnalyzeEndpoints()
+		Expect(report.PublicIPs).To(Equal([]string{"203.0.113.5"}))
+	})
+})
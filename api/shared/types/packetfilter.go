@@ -0,0 +1,178 @@
+package types
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"go4.org/netipx"
+	"tailscale.com/tailcfg"
+	"tailscale.com/types/ipproto"
+	"tailscale.com/wgengine/filter"
+)
+
+// PacketFilterReport is the filter a host enforces on incoming traffic and the capabilities it knows about from its netmap.
+type PacketFilterReport struct {
+	Host  string `json:"host,omitempty"`
+	Error string `json:"error,omitempty"`
+	// Rules come from the local client's debug packet filter rules, IE: the ACLs compiled for this node.
+	Rules        []tailcfg.FilterRule `json:"rules"`
+	ShieldsUp    bool                 `json:"shieldsUp"`
+	Addresses    []netip.Prefix       `json:"addresses"`
+	Capabilities tailcfg.NodeCapMap   `json:"capabilities,omitempty"`
+	Peers        []PeerCapabilities   `json:"peers"`
+	Attempts     []Attempt            `json:"attempts,omitempty"`
+}
+
+// PeerCapabilities is a peer from the netmap, its node capabilities are what cap: sources in the filter match against.
+type PeerCapabilities struct {
+	Name         string             `json:"name"`
+	HostName     string             `json:"hostName,omitempty"`
+	Addresses    []netip.Prefix     `json:"addresses"`
+	Capabilities tailcfg.NodeCapMap `json:"capabilities,omitempty"`
+}
+
+type FilterCheckInput struct {
+	RequestOptions
+	// Source is a host name or a Tailscale IP.
+	Source string `json:"source"`
+	// Destination is the host whose filter is evaluated.
+	Destination string `json:"destination"`
+	// Proto is tcp, udp or icmp, defaults to tcp.
+	Proto string `json:"proto,omitempty"`
+	Port  uint16 `json:"port,omitempty"`
+}
+
+// Validate rejects a check before any adapter is called, IE: TCP or UDP without a port.
+func (f *FilterCheckInput) Validate() error {
+	if f.Source == "" || f.Destination == "" {
+		return errors.New("source and destination are required")
+	}
+
+	switch f.Proto {
+	case "", "tcp", "udp":
+		if f.Port == 0 {
+			return fmt.Errorf("%s needs a port", cmp.Or(f.Proto, "tcp"))
+		}
+	case "icmp":
+	default:
+		return fmt.Errorf("unknown proto %q", f.Proto)
+	}
+	return nil
+}
+
+// FilterCheck is the verdict of a destination's filter for a single packet.
+type FilterCheck struct {
+	Source        string     `json:"source"`
+	Destination   string     `json:"destination"`
+	SourceIP      netip.Addr `json:"sourceIp"`
+	DestinationIP netip.Addr `json:"destinationIp"`
+	Proto         string     `json:"proto"`
+	Port          uint16     `json:"port,omitempty"`
+	Allowed       bool       `json:"allowed"`
+	// Reason explains a drop, IE: no rule matched.
+	Reason string `json:"reason,omitempty"`
+	// Matched are the rules that would each accept the packet on their own.
+	Matched  []tailcfg.FilterRule `json:"matched"`
+	Attempts []Attempt            `json:"attempts,omitempty"`
+}
+
+// Peer finds a peer by Tailscale IP, host name or DNS name.
+func (p *PacketFilterReport) Peer(name string) (PeerCapabilities, bool) {
+	addr, err := netip.ParseAddr(name)
+	for _, peer := range p.Peers {
+		if err == nil && slices.ContainsFunc(peer.Addresses, func(prefix netip.Prefix) bool { return prefix.Contains(addr) }) {
+			return peer, true
+		}
+		if peer.HostName == name || peer.Name == name || peer.Name == name+"." {
+			return peer, true
+		}
+	}
+	return PeerCapabilities{}, false
+}
+
+// Check evaluates the filter with the same code tailscaled runs on incoming packets.
+// The source is the first of sources in an address family the destination also has.
+func (p *PacketFilterReport) Check(sources []netip.Addr, proto string, port uint16) (*FilterCheck, error) {
+	check := &FilterCheck{
+		Destination: p.Host,
+		Proto:       proto,
+		Port:        port,
+		Matched:     []tailcfg.FilterRule{},
+	}
+	if check.Proto == "" {
+		check.Proto = "tcp"
+	}
+
+	for _, source := range sources {
+		for _, prefix := range p.Addresses {
+			if prefix.Addr().Is4() == source.Is4() && !check.SourceIP.IsValid() {
+				check.SourceIP = source
+				check.DestinationIP = prefix.Addr()
+			}
+		}
+	}
+	if !check.SourceIP.IsValid() {
+		check.Reason = "source and destination have no address family in common"
+		return check, nil
+	}
+
+	var ipProto ipproto.Proto
+	switch check.Proto {
+	case "tcp":
+		ipProto = ipproto.TCP
+	case "udp":
+		ipProto = ipproto.UDP
+	case "icmp":
+		ipProto = ipproto.ICMPv4
+		if check.SourceIP.Is6() {
+			ipProto = ipproto.ICMPv6
+		}
+		check.Port = 0
+	default:
+		return nil, fmt.Errorf("unknown proto %q", proto)
+	}
+
+	var builder netipx.IPSetBuilder
+	for _, prefix := range p.Addresses {
+		builder.AddPrefix(prefix)
+	}
+	localNets, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range p.Rules {
+		matches, err := filter.MatchesFromFilterRules([]tailcfg.FilterRule{rule})
+		if err != nil {
+			return nil, err
+		}
+
+		response := filter.New(matches, p.hasCap, localNets, nil, nil, func(string, ...any) {}).
+			Check(check.SourceIP, check.DestinationIP, check.Port, ipProto)
+		if response == filter.Accept {
+			check.Matched = append(check.Matched, rule)
+		}
+	}
+
+	check.Allowed = len(check.Matched) > 0 && !p.ShieldsUp
+	switch {
+	case p.ShieldsUp:
+		check.Reason = "shields up is on, all incoming connections are dropped"
+	case !check.Allowed:
+		check.Reason = "no rule accepts this packet"
+	}
+	return check, nil
+}
+
+// hasCap reports whether the peer at srcIP has a node capability, used by cap: sources.
+func (p *PacketFilterReport) hasCap(srcIP netip.Addr, capability tailcfg.NodeCapability) bool {
+	peer, ok := p.Peer(srcIP.String())
+	if !ok {
+		return false
+	}
+	_, ok = peer.Capabilities[capability]
+	return ok
+}
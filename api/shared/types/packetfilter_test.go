@@ -0,0 +1,92 @@
+package types
+
+import (
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/tailcfg"
+)
+
+var _ = Describe("PacketFilter", func() {
+	source := netip.MustParseAddr("100.64.0.2")
+	report := &PacketFilterReport{
+		Host:      "web",
+		Addresses: []netip.Prefix{netip.MustParsePrefix("100.64.0.1/32")},
+		Rules: []tailcfg.FilterRule{{
+			SrcIPs:   []string{"100.64.0.2/32"},
+			DstPorts: []tailcfg.NetPortRange{{IP: "*", Ports: tailcfg.PortRange{First: 443, Last: 443}}},
+			IPProto:  []int{6},
+		}, {
+			SrcIPs:   []string{"cap:admin"},
+			DstPorts: []tailcfg.NetPortRange{{IP: "100.64.0.1", Ports: tailcfg.PortRangeAny}},
+		}},
+		Peers: []PeerCapabilities{
+			{Name: "db.example.ts.net.", HostName: "db", Addresses: []netip.Prefix{netip.MustParsePrefix("100.64.0.2/32")}},
+			{Name: "admin.example.ts.net.", HostName: "admin", Addresses: []netip.Prefix{netip.MustParsePrefix("100.64.0.3/32")},
+				Capabilities: tailcfg.NodeCapMap{"admin": nil}},
+		},
+	}
+
+	It("Should accept TCP 443 but not UDP 443", func() {
+		check, err := report.Check([]netip.Addr{source}, "tcp", 443)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeTrue())
+		Expect(check.Matched).To(HaveLen(1))
+		Expect(check.DestinationIP).To(Equal(netip.MustParseAddr("100.64.0.1")))
+
+		check, err = report.Check([]netip.Addr{source}, "udp", 443)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeFalse())
+		Expect(check.Reason).To(Equal("no rule accepts this packet"))
+	})
+
+	It("Should match cap sources against peer capabilities", func() {
+		peer, ok := report.Peer("admin")
+		Expect(ok).To(BeTrue())
+
+		check, err := report.Check([]netip.Addr{peer.Addresses[0].Addr()}, "tcp", 22)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeTrue())
+
+		check, err = report.Check([]netip.Addr{source}, "tcp", 22)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeFalse())
+	})
+
+	It("Should drop everything with shields up", func() {
+		shielded := *report
+		shielded.ShieldsUp = true
+		check, err := shielded.Check([]netip.Addr{source}, "tcp", 443)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeFalse())
+		Expect(check.Matched).To(HaveLen(1))
+	})
+
+	It("Should reject unknown protocols and mismatched families", func() {
+		_, err := report.Check([]netip.Addr{source}, "sctp", 1)
+		Expect(err).To(HaveOccurred())
+
+		check, err := report.Check([]netip.Addr{netip.MustParseAddr("fd7a:115c:a1e0::2")}, "tcp", 443)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Allowed).To(BeFalse())
+		Expect(check.SourceIP.IsValid()).To(BeFalse())
+	})
+
+	It("Should reject inputs the filter can't be checked with", func() {
+		Expect((&FilterCheckInput{Source: "db", Destination: "web", Port: 443}).Validate()).To(Succeed())
+		Expect((&FilterCheckInput{Source: "db", Destination: "web", Proto: "icmp"}).Validate()).To(Succeed())
+
+		Expect((&FilterCheckInput{Destination: "web", Port: 443}).Validate()).To(HaveOccurred())
+		Expect((&FilterCheckInput{Source: "db", Destination: "web"}).Validate()).To(MatchError("tcp needs a port"))
+		Expect((&FilterCheckInput{Source: "db", Destination: "web", Proto: "udp"}).Validate()).To(MatchError("udp needs a port"))
+		Expect((&FilterCheckInput{Source: "db", Destination: "web", Proto: "sctp", Port: 1}).Validate()).To(MatchError(`unknown proto "sctp"`))
+	})
+
+	It("Should only find literal addresses that belong to a peer", func() {
+		_, ok := report.Peer("100.64.0.2")
+		Expect(ok).To(BeTrue())
+		_, ok = report.Peer("100.64.0.9")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,31 @@
+package types
+
+import "net"
+
+// RelayTarget is an adapter a relay reaches over its cluster network.
+type RelayTarget struct {
+	// Name is the path segment the relay serves the adapter under, the pod name for discovered adapters.
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// ParseRelayTargets reads a static target list, entries are name=host:port or just host:port.
+// Without a name the host is used.
+func ParseRelayTargets(entries []string) ([]RelayTarget, error) {
+	parsed, err := parseNamedEntries("relay target", entries, func(address string) error {
+		_, _, err := net.SplitHostPort(address)
+		return err
+	}, func(address string, _ int) string {
+		host, _, _ := net.SplitHostPort(address)
+		return host
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]RelayTarget, 0, len(parsed))
+	for _, entry := range parsed {
+		targets = append(targets, RelayTarget{Name: entry.Name, Address: entry.Value})
+	}
+	return targets, nil
+}
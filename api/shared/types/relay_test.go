@@ -0,0 +1,24 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RelayTargets", func() {
+	It("Should name targets by host unless a name is given", func() {
+		targets, err := ParseRelayTargets([]string{"10.0.0.5:3621", "web-0=10.0.0.6:3621"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(targets).To(Equal([]RelayTarget{
+			{Name: "10.0.0.5", Address: "10.0.0.5:3621"},
+			{Name: "web-0", Address: "10.0.0.6:3621"},
+		}))
+	})
+
+	It("Should reject targets without a port, named or not", func() {
+		for _, entry := range []string{"10.0.0.5", "web-0=10.0.0.5"} {
+			_, err := ParseRelayTargets([]string{entry})
+			Expect(err).To(HaveOccurred(), entry)
+		}
+	})
+})
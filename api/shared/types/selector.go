@@ -0,0 +1,166 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// HostInfo is what a HostSelector matches against.
+// It is built by the WebUI from the device list and each adapter's Status.Self.
+type HostInfo struct {
+	Host    string   `json:"host"`
+	Adapter string   `json:"adapter,omitempty"`
+	OS      string   `json:"os,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Online  bool     `json:"online"`
+}
+
+// HostSelector picks hosts with an expression instead of listing them.
+//
+// Terms separated by whitespace must all match, comma separated groups match if any group does.
+// Commas inside a regex, IE: name~^web-[0-9]{1,3}$, don't start a new group.
+// A term can be negated with a leading !. Supported terms:
+//
+//	all            every host
+//	online         hosts reporting online
+//	tag:<tag>      hosts with the tag, IE: tag:prod
+//	os:<os>        hosts running the os, IE: os:linux
+//	name:<name>    the host with this exact name
+//	name~<regex>   hosts with a name matching the regex, IE: name~^db-
+//
+// IE: "tag:prod os:linux !name~^db-, tag:edge" is every prod linux host that isn't a db, plus every edge host.
+type HostSelector struct {
+	expr   string
+	groups [][]selectorTerm
+}
+
+type selectorTerm struct {
+	negate bool
+	match  func(HostInfo) bool
+}
+
+func ParseHostSelector(expr string) (*HostSelector, error) {
+	selector := &HostSelector{expr: expr}
+
+	for _, rawGroup := range splitSelectorGroups(expr) {
+		fields := strings.Fields(rawGroup)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty selector group in %q", expr)
+		}
+
+		group := make([]selectorTerm, 0, len(fields))
+		for _, field := range fields {
+			term, err := parseSelectorTerm(field)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, term)
+		}
+		selector.groups = append(selector.groups, group)
+	}
+
+	return selector, nil
+}
+
+// splitSelectorGroups splits on commas outside of brackets, braces and parentheses so regex terms stay whole.
+func splitSelectorGroups(expr string) []string {
+	var groups []string
+	depth := 0
+	inClass := false
+	escaped := false
+	start := 0
+	for i, char := range expr {
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\':
+			escaped = true
+		case inClass:
+			// Anything but the closing bracket is literal inside a character class.
+			inClass = char != ']'
+		case char == '[':
+			inClass = true
+		case char == '(' || char == '{':
+			depth++
+		case char == ')' || char == '}':
+			depth = max(depth-1, 0)
+		case char == ',' && depth == 0:
+			groups = append(groups, expr[start:i])
+			start = i + 1
+		}
+	}
+	return append(groups, expr[start:])
+}
+
+func parseSelectorTerm(field string) (selectorTerm, error) {
+	term := selectorTerm{}
+	if rest, ok := strings.CutPrefix(field, "!"); ok {
+		term.negate = true
+		field = rest
+	}
+
+	if strings.HasSuffix(field, ":") || strings.HasSuffix(field, "~") {
+		return term, fmt.Errorf("selector term is missing a value: %q", field)
+	}
+
+	switch {
+	case field == "all":
+		term.match = func(HostInfo) bool { return true }
+	case field == "online":
+		term.match = func(host HostInfo) bool { return host.Online }
+	case strings.HasPrefix(field, "tag:"):
+		// Device tags keep their tag: prefix, IE: tag:prod
+		tag := field
+		term.match = func(host HostInfo) bool { return slices.Contains(host.Tags, tag) }
+	case strings.HasPrefix(field, "os:"):
+		os := strings.TrimPrefix(field, "os:")
+		term.match = func(host HostInfo) bool { return strings.EqualFold(host.OS, os) }
+	case strings.HasPrefix(field, "name:"):
+		name := strings.TrimPrefix(field, "name:")
+		term.match = func(host HostInfo) bool { return host.Host == name }
+	case strings.HasPrefix(field, "name~"):
+		regex, err := regexp.Compile(strings.TrimPrefix(field, "name~"))
+		if err != nil {
+			return term, fmt.Errorf("invalid name regex in %q: %w", field, err)
+		}
+		term.match = func(host HostInfo) bool { return regex.MatchString(host.Host) }
+	default:
+		return term, fmt.Errorf("unknown selector term: %q", field)
+	}
+
+	return term, nil
+}
+
+// Match reports whether the host is picked by the selector.
+func (s *HostSelector) Match(host HostInfo) bool {
+	for _, group := range s.groups {
+		matched := true
+		for _, term := range group {
+			if term.match(host) == term.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the names of the matching hosts in the order given.
+func (s *HostSelector) Select(hosts []HostInfo) []string {
+	selected := []string{}
+	for _, host := range hosts {
+		if s.Match(host) {
+			selected = append(selected, host.Host)
+		}
+	}
+	return selected
+}
+
+func (s *HostSelector) String() string {
+	return s.expr
+}
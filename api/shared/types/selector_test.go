@@ -0,0 +1,54 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HostSelector", func() {
+	hosts := []HostInfo{
+		{Host: "db-1", OS: "linux", Tags: []string{"tag:prod"}, Online: true},
+		{Host: "web-1", OS: "linux", Tags: []string{"tag:prod"}, Online: true},
+		{Host: "web-2", OS: "windows", Tags: []string{"tag:prod"}, Online: false},
+		{Host: "edge-1", OS: "linux", Tags: []string{"tag:edge"}, Online: true},
+	}
+
+	selectHosts := func(expr string) []string {
+		selector, err := ParseHostSelector(expr)
+		Expect(err).NotTo(HaveOccurred())
+		return selector.Select(hosts)
+	}
+
+	It("Should select every host with all", func() {
+		Expect(selectHosts("all")).To(Equal([]string{"db-1", "web-1", "web-2", "edge-1"}))
+	})
+
+	It("Should require every term in a group to match", func() {
+		Expect(selectHosts("tag:prod os:linux online")).To(Equal([]string{"db-1", "web-1"}))
+	})
+
+	It("Should match any comma separated group", func() {
+		Expect(selectHosts("name:web-2, tag:edge")).To(Equal([]string{"web-2", "edge-1"}))
+	})
+
+	It("Should support negation and regex names", func() {
+		Expect(selectHosts("tag:prod !name~^db-")).To(Equal([]string{"web-1", "web-2"}))
+		Expect(selectHosts("all !online")).To(Equal([]string{"web-2"}))
+	})
+
+	It("Should keep commas inside a regex in the same group", func() {
+		Expect(selectHosts("name~^web-[0-9]{1,3}$, name:db-1")).To(Equal([]string{"db-1", "web-1", "web-2"}))
+		Expect(selectHosts(`name~^(edge|db)-[,1]`)).To(Equal([]string{"db-1", "edge-1"}))
+	})
+
+	It("Should match os case insensitively", func() {
+		Expect(selectHosts("os:Windows")).To(Equal([]string{"web-2"}))
+	})
+
+	It("Should reject invalid expressions", func() {
+		for _, expr := range []string{"", "tag:prod,", "bogus", "tag:", "name~(", "name~"} {
+			_, err := ParseHostSelector(expr)
+			Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+})
@@ -0,0 +1,173 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"tailscale.com/ipn"
+)
+
+var serveProxySchemes = []string{"http", "https", "https+insecure"}
+
+// ServeHandler is a single TCP or web handler out of a ServeConfig.
+type ServeHandler struct {
+	// Service is set for handlers of a Tailscale Service instead of the node itself.
+	Service string `json:"service,omitempty"`
+	// Foreground is the session of a foreground `tailscale serve`, empty for the background config.
+	Foreground string `json:"foreground,omitempty"`
+	// Kind is web or tcp.
+	Kind     string `json:"kind"`
+	HostName string `json:"hostName,omitempty"`
+	Port     uint16 `json:"port"`
+	Path     string `json:"path,omitempty"`
+	// TargetKind is proxy, text, path, redirect, tcp-forward or terminate-tls.
+	TargetKind string `json:"targetKind"`
+	Target     string `json:"target,omitempty"`
+	Funnel     bool   `json:"funnel"`
+}
+
+// ServeProbeResult is a local TCP dial to a proxy or forward target.
+type ServeProbeResult struct {
+	Target    string        `json:"target"`
+	Address   string        `json:"address"`
+	Reachable bool          `json:"reachable"`
+	Latency   time.Duration `json:"latency,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	// Skipped is why the target wasn't dialed, Reachable means nothing when set.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// FlattenServeConfig lists every handler, sorted so two configs can be compared line by line.
+func FlattenServeConfig(config *ipn.ServeConfig) []ServeHandler {
+	handlers := []ServeHandler{}
+	if config == nil {
+		return handlers
+	}
+
+	add := func(service, foreground string, tcp map[uint16]*ipn.TCPPortHandler, web map[ipn.HostPort]*ipn.WebServerConfig) {
+		for port, handler := range tcp {
+			// HTTP and HTTPS listeners are covered by their web handlers.
+			if handler == nil || handler.TCPForward == "" {
+				continue
+			}
+			serveHandler := ServeHandler{
+				Service:    service,
+				Foreground: foreground,
+				Kind:       "tcp",
+				Port:       port,
+				TargetKind: "tcp-forward",
+				Target:     handler.TCPForward,
+			}
+			if handler.TerminateTLS != "" {
+				serveHandler.TargetKind = "terminate-tls"
+				serveHandler.HostName = handler.TerminateTLS
+			}
+			serveHandler.Funnel = funnelOnPort(config, port)
+			handlers = append(handlers, serveHandler)
+		}
+
+		for hostPort, webConfig := range web {
+			if webConfig == nil {
+				continue
+			}
+			hostName, _, _ := net.SplitHostPort(string(hostPort))
+			port, _ := hostPort.Port()
+			for mount, handler := range webConfig.Handlers {
+				if handler == nil {
+					continue
+				}
+				serveHandler := ServeHandler{
+					Service:    service,
+					Foreground: foreground,
+					Kind:       "web",
+					HostName:   hostName,
+					Port:       port,
+					Path:       mount,
+					Funnel:     config.AllowFunnel[hostPort],
+				}
+				switch {
+				case handler.Proxy != "":
+					serveHandler.TargetKind, serveHandler.Target = "proxy", handler.Proxy
+				case handler.Path != "":
+					serveHandler.TargetKind, serveHandler.Target = "path", handler.Path
+				case handler.Redirect != "":
+					serveHandler.TargetKind, serveHandler.Target = "redirect", handler.Redirect
+				default:
+					serveHandler.TargetKind, serveHandler.Target = "text", handler.Text
+				}
+				handlers = append(handlers, serveHandler)
+			}
+		}
+	}
+
+	add("", "", config.TCP, config.Web)
+	for name, service := range config.Services {
+		if service != nil {
+			add(string(name), "", service.TCP, service.Web)
+		}
+	}
+	for session, foreground := range config.Foreground {
+		if foreground != nil {
+			add("", session, foreground.TCP, foreground.Web)
+		}
+	}
+
+	slices.SortFunc(handlers, func(a, b ServeHandler) int {
+		return strings.Compare(a.sortKey(), b.sortKey())
+	})
+	return handlers
+}
+
+func funnelOnPort(config *ipn.ServeConfig, port uint16) bool {
+	for hostPort, allowed := range config.AllowFunnel {
+		if funnelPort, err := hostPort.Port(); err == nil && funnelPort == port && allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ServeHandler) sortKey() string {
+	return fmt.Sprintf("%s %s %05d %s %s", s.Service, s.Foreground, s.Port, s.Kind, s.Path)
+}
+
+// Key describes the handler without the node's own host name, so the same setup on two hosts has the same key.
+func (s ServeHandler) Key() string {
+	key := fmt.Sprintf("%s :%d%s %s=%s", s.Kind, s.Port, s.Path, s.TargetKind, s.Target)
+	if s.Service != "" {
+		key = s.Service + " " + key
+	}
+	if s.Funnel {
+		key += " funnel"
+	}
+	return key
+}
+
+// ProbeAddress is the host:port a proxy or forward target dials, empty when there is nothing to dial.
+func (s ServeHandler) ProbeAddress() string {
+	switch s.TargetKind {
+	case "tcp-forward", "terminate-tls":
+		return s.Target
+	case "proxy":
+		expanded, err := ipn.ExpandProxyTargetValue(s.Target, serveProxySchemes, "http")
+		if err != nil {
+			return ""
+		}
+		target, err := url.Parse(expanded)
+		if err != nil {
+			return ""
+		}
+		if target.Port() != "" {
+			return target.Host
+		}
+		if target.Scheme == "http" {
+			return net.JoinHostPort(target.Hostname(), "80")
+		}
+		return net.JoinHostPort(target.Hostname(), "443")
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn"
+	"tailscale.com/tailcfg"
+)
+
+var _ = Describe("Serve", func() {
+	config := &ipn.ServeConfig{
+		TCP: map[uint16]*ipn.TCPPortHandler{
+			443: {HTTPS: true},
+			22:  {TCPForward: "127.0.0.1:2222"},
+		},
+		Web: map[ipn.HostPort]*ipn.WebServerConfig{
+			"web.example.ts.net:443": {Handlers: map[string]*ipn.HTTPHandler{
+				"/":      {Proxy: "http://127.0.0.1:3000"},
+				"/hello": {Text: "hi"},
+			}},
+		},
+		AllowFunnel: map[ipn.HostPort]bool{"web.example.ts.net:443": true},
+		Services: map[tailcfg.ServiceName]*ipn.ServiceConfig{
+			"svc:db": {TCP: map[uint16]*ipn.TCPPortHandler{5432: {TCPForward: "localhost:5432"}}},
+		},
+	}
+
+	It("Should flatten every handler in a stable order", func() {
+		handlers := FlattenServeConfig(config)
+		Expect(handlers).To(HaveLen(4))
+
+		Expect(handlers[0].Kind).To(Equal("tcp"))
+		Expect(handlers[0].Port).To(BeEquivalentTo(22))
+		Expect(handlers[1].Path).To(Equal("/"))
+		Expect(handlers[1].HostName).To(Equal("web.example.ts.net"))
+		Expect(handlers[1].Funnel).To(BeTrue())
+		Expect(handlers[2].TargetKind).To(Equal("text"))
+		Expect(handlers[3].Service).To(Equal("svc:db"))
+	})
+
+	It("Should key handlers without the host name", func() {
+		handlers := FlattenServeConfig(config)
+		Expect(handlers[1].Key()).To(Equal("web :443/ proxy=http://127.0.0.1:3000 funnel"))
+		Expect(handlers[3].Key()).To(Equal("svc:db tcp :5432 tcp-forward=localhost:5432"))
+	})
+
+	It("Should find the address to probe", func() {
+		Expect(ServeHandler{TargetKind: "proxy", Target: "3000"}.ProbeAddress()).To(Equal("127.0.0.1:3000"))
+		Expect(ServeHandler{TargetKind: "proxy", Target: "https+insecure://localhost:8443/api"}.ProbeAddress()).To(Equal("localhost:8443"))
+		Expect(ServeHandler{TargetKind: "proxy", Target: "http://backend.internal"}.ProbeAddress()).To(Equal("backend.internal:80"))
+		Expect(ServeHandler{TargetKind: "tcp-forward", Target: "127.0.0.1:2222"}.ProbeAddress()).To(Equal("127.0.0.1:2222"))
+		Expect(ServeHandler{TargetKind: "text", Target: "hi"}.ProbeAddress()).To(BeEmpty())
+	})
+
+	It("Should handle an empty config", func() {
+		Expect(FlattenServeConfig(nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+)
+
+// TailnetLockStatus is a host's view of Tailnet Lock, trimmed from ipnstate.NetworkLockStatus.
+type TailnetLockStatus struct {
+	Host          string `json:"host,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Enabled       bool   `json:"enabled"`
+	Head          string `json:"head,omitempty"`
+	PublicKey     string `json:"publicKey,omitempty"`
+	NodeKey       string `json:"nodeKey,omitempty"`
+	NodeKeySigned bool   `json:"nodeKeySigned"`
+	// LockedOut is set when lock is enabled and this node's key isn't signed, every locked peer drops its traffic.
+	LockedOut     bool   `json:"lockedOut"`
+	SignatureKind string `json:"signatureKind,omitempty"`
+	// SignedBy is the key that authorized this node's signature.
+	SignedBy string `json:"signedBy,omitempty"`
+	// SignedByTrustedKey is false when the signing key has since been removed from the trusted keys.
+	SignedByTrustedKey bool              `json:"signedByTrustedKey"`
+	TrustedKeys        []TailnetLockKey  `json:"trustedKeys"`
+	VisiblePeers       int               `json:"visiblePeers"`
+	FilteredPeers      []TailnetLockPeer `json:"filteredPeers"`
+	Attempts           []Attempt         `json:"attempts,omitempty"`
+}
+
+type TailnetLockKey struct {
+	Key      string            `json:"key"`
+	Votes    uint              `json:"votes"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// TailnetLockPeer is a peer this host drops because its node key signature didn't verify.
+type TailnetLockPeer struct {
+	Name     string               `json:"name"`
+	StableID tailcfg.StableNodeID `json:"stableId"`
+	NodeKey  string               `json:"nodeKey"`
+}
+
+func NewTailnetLockStatus(status *ipnstate.NetworkLockStatus) TailnetLockStatus {
+	lock := TailnetLockStatus{
+		Enabled:       status.Enabled,
+		NodeKeySigned: status.NodeKeySigned,
+		LockedOut:     status.Enabled && !status.NodeKeySigned,
+		TrustedKeys:   []TailnetLockKey{},
+		VisiblePeers:  len(status.VisiblePeers),
+		FilteredPeers: []TailnetLockPeer{},
+	}
+	if status.Head != nil {
+		lock.Head = fmt.Sprintf("%x", *status.Head)
+	}
+	if !status.PublicKey.IsZero() {
+		lock.PublicKey = status.PublicKey.CLIString()
+	}
+	if status.NodeKey != nil {
+		lock.NodeKey = status.NodeKey.String()
+	}
+
+	for _, trusted := range status.TrustedKeys {
+		lock.TrustedKeys = append(lock.TrustedKeys, TailnetLockKey{
+			Key:      trusted.Key.CLIString(),
+			Votes:    trusted.Votes,
+			Metadata: trusted.Metadata,
+		})
+	}
+
+	if status.NodeKeySignature != nil {
+		lock.SignatureKind = status.NodeKeySignature.SigKind.String()
+		keyID, err := status.NodeKeySignature.UnverifiedAuthorizingKeyID()
+		if err == nil {
+			lock.SignedBy = fmt.Sprintf("%x", keyID)
+			for _, trusted := range status.TrustedKeys {
+				if bytes.Equal(trusted.Key.KeyID(), keyID) {
+					lock.SignedBy = trusted.Key.CLIString()
+					lock.SignedByTrustedKey = true
+				}
+			}
+		}
+	}
+
+	for _, peer := range status.FilteredPeers {
+		lock.FilteredPeers = append(lock.FilteredPeers, TailnetLockPeer{
+			Name:     peer.Name,
+			StableID: peer.StableID,
+			NodeKey:  peer.NodeKey.String(),
+		})
+	}
+	return lock
+}
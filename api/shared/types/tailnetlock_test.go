@@ -0,0 +1,52 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tka"
+	"tailscale.com/types/key"
+)
+
+var _ = Describe("TailnetLock", func() {
+	trusted := key.NewNLPrivate()
+	removed := key.NewNLPrivate()
+	nodeKey := key.NewNode().Public()
+
+	It("Should report a node signed by a trusted key", func() {
+		lock := NewTailnetLockStatus(&ipnstate.NetworkLockStatus{
+			Enabled:          true,
+			NodeKey:          &nodeKey,
+			NodeKeySigned:    true,
+			NodeKeySignature: &tka.NodeKeySignature{SigKind: tka.SigDirect, KeyID: trusted.KeyID()},
+			TrustedKeys:      []ipnstate.TKAKey{{Key: trusted.Public(), Votes: 1}},
+			FilteredPeers:    []*ipnstate.TKAPeer{{Name: "rogue.example.ts.net.", StableID: "nABC"}},
+		})
+
+		Expect(lock.LockedOut).To(BeFalse())
+		Expect(lock.SignatureKind).To(Equal("direct"))
+		Expect(lock.SignedBy).To(Equal(trusted.Public().CLIString()))
+		Expect(lock.SignedByTrustedKey).To(BeTrue())
+		Expect(lock.FilteredPeers).To(HaveLen(1))
+		Expect(lock.FilteredPeers[0].StableID).To(BeEquivalentTo("nABC"))
+	})
+
+	It("Should report locked out nodes and untrusted signing keys", func() {
+		lock := NewTailnetLockStatus(&ipnstate.NetworkLockStatus{
+			Enabled:          true,
+			NodeKey:          &nodeKey,
+			NodeKeySignature: &tka.NodeKeySignature{SigKind: tka.SigDirect, KeyID: removed.KeyID()},
+			TrustedKeys:      []ipnstate.TKAKey{{Key: trusted.Public(), Votes: 1}},
+		})
+
+		Expect(lock.LockedOut).To(BeTrue())
+		Expect(lock.SignedByTrustedKey).To(BeFalse())
+		Expect(lock.SignedBy).ToNot(BeEmpty())
+	})
+
+	It("Should not report locked out when lock is disabled", func() {
+		lock := NewTailnetLockStatus(&ipnstate.NetworkLockStatus{})
+		Expect(lock.LockedOut).To(BeFalse())
+		Expect(lock.TrustedKeys).To(BeEmpty())
+	})
+})
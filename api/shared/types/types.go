@@ -1,7 +1,10 @@
 package types
 
 import (
+	"encoding"
+	"encoding/json"
 	"reflect"
+	"time"
 
 	"tailscale.com/ipn"
 )
@@ -17,8 +20,25 @@ type ServeConfig struct {
 	ETag string `json:"etag,omitempty"`
 }
 
+// RequestOptions tune how the WebUI fans a request out to adapters, they are clamped to the server maximums.
+type RequestOptions struct {
+	// Timeout bounds the call to each host, retries included, IE: 10s
+	Timeout string `json:"timeout,omitempty"`
+	// Retries are only made against endpoints that are safe to call again, IE: not BugReport or Pprof.
+	Retries     int `json:"retries,omitempty"`
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// Attempt records a single call made to an adapter.
+type Attempt struct {
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
 type QueryDNSInput struct {
+	RequestOptions
 	Hosts     []string `json:"hosts,omitempty"`
+	Selector  string   `json:"selector,omitempty"`
 	Name      string   `json:"name"`
 	QueryType string   `json:"queryType"`
 }
@@ -37,6 +57,7 @@ type QueryDNSResult struct {
 	Header    DNSHeader `json:"header"`
 	Responses []string  `json:"responses,omitempty"`
 	Resolvers []string  `json:"resolvers"`
+	Attempts  []Attempt `json:"attempts,omitempty"`
 }
 
 type PingInput struct {
@@ -46,15 +67,60 @@ type PingInput struct {
 	Delay    string `json:"delay"`
 }
 
+type BugReportInput struct {
+	RequestOptions
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// Note is logged with the marker, IE: the support ticket number.
+	Note     string `json:"note,omitempty"`
+	Diagnose bool   `json:"diagnose,omitempty"`
+}
+
+// BugReportResult carries the log marker to quote to Tailscale support.
+type BugReportResult struct {
+	Error    string    `json:"error,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Marker   string    `json:"marker,omitempty"`
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+type DERPProbeInput struct {
+	RequestOptions
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// Regions to probe by ID or code, every region in the DERP map when empty.
+	Regions []string `json:"regions,omitempty"`
+}
+
+// DERPRegionProbe is the result of the debug DERP region check.
+type DERPRegionProbe struct {
+	RegionID   int    `json:"regionId"`
+	RegionCode string `json:"regionCode"`
+	RegionName string `json:"regionName,omitempty"`
+	// LatencyV4 and LatencyV6 are the fastest recent STUN round trips to the region from tailscaled's netcheck,
+	// zero when the region wasn't reachable over that address family.
+	LatencyV4 time.Duration `json:"latencyV4,omitempty"`
+	LatencyV6 time.Duration `json:"latencyV6,omitempty"`
+	// CheckDuration is how long the whole check took, STUN and a DERP connection, it is not a round trip time.
+	CheckDuration time.Duration `json:"checkDuration"`
+	Info          []string      `json:"info,omitempty"`
+	Warnings      []string      `json:"warnings,omitempty"`
+	Errors        []string      `json:"errors,omitempty"`
+}
+
 type PprofInput struct {
-	Hosts   []string `json:"hosts,omitempty"`
-	Type    string   `json:"type"`
-	Seconds int      `json:"seconds"`
+	RequestOptions
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	Type     string   `json:"type"`
+	Seconds  int      `json:"seconds"`
 }
 
 // StructToMap recursively converts a struct to a map[string]any
 // This may seem messy, but using this means i don't have to change backend logic most of the time when underlying structs change.
 // It also means that the UI gets access to fields it wouldn't normally see if we tried to just serialize them normally.
+// Structs that encode themselves are left to their own encoding, so Status.Self sends times as RFC 3339 strings,
+// keys as their text form and views as arrays, the same as every entry of Status.Peer.
 func StructToMap(obj any) map[string]any {
 	result := make(map[string]any)
 
@@ -86,7 +152,8 @@ func StructToMap(obj any) map[string]any {
 			continue
 		}
 
-		if fieldValueKind == reflect.Struct {
+		// Structs that know how to encode themselves are kept as is, IE: time.Time and views.Slice.
+		if fieldValueKind == reflect.Struct && !selfEncoding(tmpval.Type()) {
 			fieldValue = StructToMap(tmpval.Interface())
 		} else {
 			fieldValue = tmpval.Interface()
@@ -97,3 +164,12 @@ func StructToMap(obj any) map[string]any {
 
 	return result
 }
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func selfEncoding(typ reflect.Type) bool {
+	return typ.Implements(jsonMarshaler) || typ.Implements(textMarshaler)
+}
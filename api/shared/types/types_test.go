@@ -0,0 +1,42 @@
+package types
+
+import (
+	"encoding/json"
+	"net/netip"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/types/key"
+	"tailscale.com/types/views"
+)
+
+var _ = Describe("StructToMap", func() {
+	It("Should keep structs that encode themselves", func() {
+		expiry := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		routes := views.SliceOf([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")})
+		nodeKey := key.NewNode().Public()
+
+		status := &ipnstate.Status{
+			Version: "1.92.4",
+			Self: &ipnstate.PeerStatus{
+				HostName:      "web-1",
+				PublicKey:     nodeKey,
+				KeyExpiry:     &expiry,
+				PrimaryRoutes: &routes,
+			},
+		}
+
+		data, err := json.Marshal(StructToMap(status))
+		Expect(err).NotTo(HaveOccurred())
+
+		decoded := &ipnstate.Status{}
+		Expect(json.Unmarshal(data, decoded)).To(Succeed())
+		Expect(decoded.Version).To(Equal("1.92.4"))
+		Expect(decoded.Self.HostName).To(Equal("web-1"))
+		Expect(decoded.Self.PublicKey).To(Equal(nodeKey))
+		Expect(decoded.Self.KeyExpiry.Equal(expiry)).To(BeTrue())
+		Expect(decoded.Self.PrimaryRoutes.AsSlice()).To(Equal(routes.AsSlice()))
+	})
+})
@@ -6,15 +6,35 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
 	"slices"
+	"strings"
 	"sync"
 
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/hashicorp/yamux"
 	"github.com/spf13/viper"
 	"tailscale.com/tsnet"
 )
 
+// ErrUnknownHost is returned by CallHost when no adapter is known for the target host.
+var ErrUnknownHost = errors.New("failed to find adapter for host")
+
+// ErrTunnelTaken is returned by SetTunnel when a different node already holds a live tunnel under the adapter's name.
+var ErrTunnelTaken = errors.New("adapter name is already tunneled by another node")
+
+// StatusError is returned by CallAdapter when the adapter responds with anything but a 200.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
 // Maintain a two way mapping to allow fast lookups in both directions.
 // There's probably a much cleaner way to do this, but it's barely necessary to start with.
 type Client struct {
@@ -23,12 +43,93 @@ type Client struct {
 	adapters sync.Map
 	// map[host]adapter
 	hosts sync.Map
+	// Adapters that dialed in, keyed by adapter name.
+	tunnelsMu sync.Mutex
+	tunnels   map[string]tunnel
+
+	httpClient *http.Client
 }
 
 func NewClient(tsnet *tsnet.Server) *Client {
-	return &Client{
-		Server: tsnet,
+	c := &Client{
+		Server:  tsnet,
+		tunnels: map[string]tunnel{},
+	}
+	c.httpClient = &http.Client{
+		Transport: &http.Transport{DialContext: c.DialAdapter},
+	}
+	return c
+}
+
+// tunnel is a session an adapter opened to us, node is the stable ID of the node that opened it.
+type tunnel struct {
+	session *yamux.Session
+	node    string
+}
+
+// SetTunnel routes every call to the adapter over a session it opened to us.
+// A live tunnel under the same name from a different node is never replaced, only dev mode lets adapters pick their own name.
+func (c *Client) SetTunnel(adapter string, node string, session *yamux.Session) error {
+	c.tunnelsMu.Lock()
+	defer c.tunnelsMu.Unlock()
+
+	previous, ok := c.tunnels[adapter]
+	if ok && previous.node != node && !previous.session.IsClosed() {
+		return fmt.Errorf("%w: %s", ErrTunnelTaken, adapter)
+	}
+	if ok && previous.session != session {
+		previous.session.Close()
+	}
+	c.tunnels[adapter] = tunnel{session: session, node: node}
+
+	// Pooled connections to the adapter are streams on whatever session it had before.
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// DeleteTunnel forgets the session unless the adapter has already reconnected with a new one.
+func (c *Client) DeleteTunnel(adapter string, session *yamux.Session) {
+	c.tunnelsMu.Lock()
+	defer c.tunnelsMu.Unlock()
+
+	if current, ok := c.tunnels[adapter]; ok && current.session == session {
+		delete(c.tunnels, adapter)
+		c.httpClient.CloseIdleConnections()
+	}
+}
+
+func (c *Client) GetTunnels() []string {
+	c.tunnelsMu.Lock()
+	defer c.tunnelsMu.Unlock()
+
+	return slices.Collect(maps.Keys(c.tunnels))
+}
+
+// DialAdapter opens a stream over the adapter's tunnel when it has one, otherwise it dials over tsnet.
+func (c *Client) DialAdapter(ctx context.Context, network string, address string) (net.Conn, error) {
+	adapter, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tunnelsMu.Lock()
+	current, ok := c.tunnels[adapter]
+	c.tunnelsMu.Unlock()
+
+	if ok && !current.session.IsClosed() {
+		return current.session.Open()
+	}
+	return c.Server.Dial(ctx, network, address)
+}
+
+// SplitAdapter separates the device to dial from the path prefix of an adapter served behind another device.
+// A relay's adapters are named <relay>/relay/<target> and an adapter's instances <adapter>/instance/<name>, plain adapters have no prefix.
+func SplitAdapter(adapter string) (string, string) {
+	device, prefix, found := strings.Cut(adapter, "/")
+	if !found {
+		return adapter, ""
 	}
+	return device, "/" + prefix
 }
 
 func (c *Client) SetKnownHost(host string, adapter string) {
@@ -84,13 +185,14 @@ func (c *Client) GetHosts() []string {
 
 func (c *Client) CallAdapter(ctx context.Context, r *tsymbiote.HTTPRequest, method string, adapter string, path string, body []byte) (io.ReadCloser, error) {
 
-	client := c.HTTPClient()
+	client := c.httpClient
 
 	bodyReader := bytes.NewReader(body)
 
 	port := viper.GetString("adapter-port")
+	device, prefix := SplitAdapter(adapter)
 
-	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://%s:%s%s", adapter, port, path), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("http://%s:%s%s%s", device, port, prefix, path), bodyReader)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +209,7 @@ func (c *Client) CallAdapter(ctx context.Context, r *tsymbiote.HTTPRequest, meth
 
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
-		return nil, errors.New(resp.Status)
+		return nil, &StatusError{Code: resp.StatusCode, Status: resp.Status}
 	}
 
 	return resp.Body, nil
@@ -119,7 +221,7 @@ func (c *Client) CallHost(ctx context.Context, r *tsymbiote.HTTPRequest, method
 	// Fetch the Adapter to call using the target hostname
 	adapter, ok := c.GetAdapter(host)
 	if !ok || adapter == "" {
-		return nil, fmt.Errorf("failed to find adapter for host: %s", host)
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHost, host)
 	}
 
 	resp, err := c.CallAdapter(ctx, r, method, adapter, path, body)
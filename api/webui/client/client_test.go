@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+var _ = Describe("SplitAdapter", func() {
+	It("Should split relayed adapters and instances from the device to dial", func() {
+		for adapter, want := range map[string][2]string{
+			"adapter-1":                         {"adapter-1", ""},
+			"adapter-1/instance/proxy":          {"adapter-1", "/instance/proxy"},
+			"relay-1/relay/web-0":               {"relay-1", "/relay/web-0"},
+			"relay-1/relay/web-0/instance/1234": {"relay-1", "/relay/web-0/instance/1234"},
+		} {
+			device, prefix := SplitAdapter(adapter)
+			Expect([2]string{device, prefix}).To(Equal(want), adapter)
+		}
+	})
+})
+
+var _ = Describe("CallAdapter", func() {
+	It("Should dial the device and call the path under the adapter's prefix", func() {
+		var dialed string
+		var host, path string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, path = r.Host, r.URL.Path
+		}))
+		DeferCleanup(server.Close)
+
+		viper.Set("adapter-port", "3621")
+		DeferCleanup(viper.Set, "adapter-port", "")
+
+		// Every device resolves to the test server, the address asked for is what we check.
+		c := &Client{tunnels: map[string]tunnel{}}
+		c.httpClient = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				dialed = address
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		}}
+
+		resp, err := c.CallAdapter(context.Background(), &tsymbiote.HTTPRequest{}, "POST", "relay-1/relay/web-0/instance/proxy", paths.Status.Adapter(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		io.Copy(io.Discard, resp)
+		resp.Close()
+
+		Expect(dialed).To(Equal("relay-1:3621"))
+		Expect(host).To(Equal("relay-1:3621"))
+		Expect(path).To(Equal("/relay/web-0/instance/proxy" + paths.Status.Adapter()))
+	})
+})
@@ -0,0 +1,217 @@
+package expectation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/parser"
+)
+
+// expectationCostLimit bounds how much work a single evaluation can do, CEL is not turing complete but lists can be large.
+const expectationCostLimit = 1_000_000
+
+// Expectation is a CEL expression evaluated against every host result of a fan-out endpoint.
+//
+// The expression sees two variables, host is the hostname and result is the endpoint result for that host.
+// When the host result wraps the adapter response in a result field, IE: Status or Prefs, result is the unwrapped response.
+//
+//	result.CorpDNS
+//	result.Self.Online
+//	"10.0.0.5" in result.responses
+//	result.results.all(r, r.LatencySeconds < 0.05)
+//
+// When an expectation fails the offending value is reported, this is the left side of a comparison,
+// the list of an all/exists, or the first failing side of an &&.
+type Expectation struct {
+	expr string
+	root *expectationNode
+}
+
+// expectationNode pairs a boolean program with the program that reports the offending value.
+type expectationNode struct {
+	check cel.Program
+	value cel.Program
+	// and holds the sides of an && so the failing one can be reported.
+	and []*expectationNode
+}
+
+func expectationEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("host", cel.StringType),
+		cel.Variable("result", cel.DynType),
+		// Needed to print all() and exists() back to source.
+		cel.EnableMacroCallTracking(),
+	)
+}
+
+func NewExpectation(expr string) (*Expectation, error) {
+	env, err := expectationEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expectation must be a bool, got %s", ast.OutputType())
+	}
+
+	root, err := newExpectationNode(env, ast.NativeRep().Expr(), ast.NativeRep().SourceInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expectation{expr: expr, root: root}, nil
+}
+
+func newExpectationNode(env *cel.Env, expr celast.Expr, info *celast.SourceInfo) (*expectationNode, error) {
+	check, err := expectationProgram(env, expr, info)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &expectationNode{check: check}
+
+	if expr.Kind() == celast.CallKind && expr.AsCall().FunctionName() == operators.LogicalAnd {
+		for _, arg := range expr.AsCall().Args() {
+			child, err := newExpectationNode(env, arg, info)
+			if err != nil {
+				return nil, err
+			}
+			node.and = append(node.and, child)
+		}
+		return node, nil
+	}
+
+	node.value, err = expectationProgram(env, offendingExpr(expr), info)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// offendingExpr picks the part of an expression worth showing when it evaluates to false.
+func offendingExpr(expr celast.Expr) celast.Expr {
+	switch expr.Kind() {
+	case celast.CallKind:
+		call := expr.AsCall()
+		switch call.FunctionName() {
+		case operators.Equals, operators.NotEquals, operators.Less, operators.LessEquals,
+			operators.Greater, operators.GreaterEquals, operators.In:
+			return call.Args()[0]
+		case operators.LogicalNot:
+			return offendingExpr(call.Args()[0])
+		}
+	case celast.ComprehensionKind:
+		// all() and exists() expand to a comprehension, show the list they ran over.
+		return expr.AsComprehension().IterRange()
+	}
+	return expr
+}
+
+// expectationProgram compiles a sub expression on its own by printing it back to source.
+func expectationProgram(env *cel.Env, expr celast.Expr, info *celast.SourceInfo) (cel.Program, error) {
+	source, err := parser.Unparse(expr, info)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(source)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	return env.Program(ast, cel.CostLimit(expectationCostLimit), cel.InterruptCheckFrequency(100))
+}
+
+func (a *Expectation) String() string {
+	return a.expr
+}
+
+// Evaluate checks a single host result as returned by a fan-out endpoint.
+func (a *Expectation) Evaluate(ctx context.Context, record map[string]any) types.ExpectationResult {
+	result := types.ExpectationResult{}
+	result.Host, _ = record["host"].(string)
+	result.Target, _ = record["target"].(string)
+
+	if hostErr, _ := record["error"].(string); hostErr != "" {
+		result.Error = hostErr
+		return result
+	}
+
+	vars := map[string]any{
+		"host":   result.Host,
+		"result": record,
+	}
+	if wrapped, ok := record["result"]; ok {
+		vars["result"] = wrapped
+	}
+
+	passed, err := a.root.eval(ctx, vars)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Passed = passed
+	if !passed {
+		result.Value = a.root.offendingValue(ctx, vars)
+	}
+	return result
+}
+
+// EvaluateAll checks every host result of a fan-out endpoint response.
+func (a *Expectation) EvaluateAll(ctx context.Context, results json.RawMessage) ([]types.ExpectationResult, error) {
+	records := []map[string]any{}
+	err := json.Unmarshal(results, &records)
+	if err != nil {
+		return nil, fmt.Errorf("expectations need a list of host results: %w", err)
+	}
+
+	out := make([]types.ExpectationResult, 0, len(records))
+	for _, record := range records {
+		out = append(out, a.Evaluate(ctx, record))
+	}
+	return out, nil
+}
+
+func (n *expectationNode) eval(ctx context.Context, vars map[string]any) (bool, error) {
+	out, _, err := n.check.ContextEval(ctx, vars)
+	if err != nil {
+		return false, err
+	}
+
+	passed, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.New("expectation did not evaluate to a bool")
+	}
+	return passed, nil
+}
+
+func (n *expectationNode) offendingValue(ctx context.Context, vars map[string]any) any {
+	for _, child := range n.and {
+		passed, err := child.eval(ctx, vars)
+		if err != nil || !passed {
+			return child.offendingValue(ctx, vars)
+		}
+	}
+
+	if n.value == nil {
+		return nil
+	}
+
+	out, _, err := n.value.ContextEval(ctx, vars)
+	if err != nil {
+		return err.Error()
+	}
+	return out.Value()
+}
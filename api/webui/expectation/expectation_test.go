@@ -0,0 +1,60 @@
+package expectation
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expectation", func() {
+	evaluate := func(expr string, results string) []types.ExpectationResult {
+		expectation, err := NewExpectation(expr)
+		Expect(err).NotTo(HaveOccurred())
+		out, err := expectation.EvaluateAll(context.Background(), json.RawMessage(results))
+		Expect(err).NotTo(HaveOccurred())
+		return out
+	}
+
+	It("Should unwrap passthrough results", func() {
+		out := evaluate("result.CorpDNS", `[{"host":"a","result":{"CorpDNS":true}},{"host":"b","result":{"CorpDNS":false}}]`)
+		Expect(out).To(Equal([]types.ExpectationResult{
+			{Host: "a", Passed: true},
+			{Host: "b", Passed: false, Value: false},
+		}))
+	})
+
+	It("Should report the left side of a failed comparison", func() {
+		out := evaluate(`result.header.responseCode == "NoError"`, `[{"host":"a","header":{"responseCode":"NXDomain"}}]`)
+		Expect(out[0].Passed).To(BeFalse())
+		Expect(out[0].Value).To(Equal("NXDomain"))
+	})
+
+	It("Should report the failing side of an and", func() {
+		out := evaluate(`result.Self.Online && result.Self.OS == "linux"`, `[{"host":"a","result":{"Self":{"Online":true,"OS":"windows"}}}]`)
+		Expect(out[0].Value).To(Equal("windows"))
+	})
+
+	It("Should report the list of a failed macro", func() {
+		out := evaluate(`result.results.all(r, r.LatencySeconds < 0.05)`, `[{"host":"a","target":"b","results":[{"LatencySeconds":0.2}]}]`)
+		Expect(out[0].Target).To(Equal("b"))
+		Expect(out[0].Passed).To(BeFalse())
+		Expect(out[0].Value).To(Equal([]any{map[string]any{"LatencySeconds": 0.2}}))
+	})
+
+	It("Should fail hosts that errored or are missing fields", func() {
+		out := evaluate("result.CorpDNS", `[{"host":"a","error":"timeout"},{"host":"b","result":{}}]`)
+		Expect(out[0]).To(Equal(types.ExpectationResult{Host: "a", Error: "timeout"}))
+		Expect(out[1].Passed).To(BeFalse())
+		Expect(out[1].Error).NotTo(BeEmpty())
+	})
+
+	It("Should reject expressions that aren't bools or don't compile", func() {
+		for _, expr := range []string{`host + "x"`, `result.`, `1`} {
+			_, err := NewExpectation(expr)
+			Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+})
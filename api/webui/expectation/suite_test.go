@@ -0,0 +1,14 @@
+package expectation
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestExpectation(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "Expectation Suite")
+}
@@ -0,0 +1,144 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/gorilla/websocket"
+)
+
+const adapterTag = "tag:tsymbiote-adapter"
+
+// refreshKnownHosts re-resolves the host -> adapter mapping from the device list.
+// Concurrent callers share one refresh, and a refresh within KnownHostsRefreshTTL of the last is skipped,
+// so a wave of reconnecting streams doesn't fan out to every adapter once per stream.
+func (t *TSymbioteUIServer) refreshKnownHosts(ctx context.Context, r *tsymbiote.HTTPRequest) error {
+	if time.Since(time.Unix(0, t.hostsRefreshedAt.Load())) < consts.KnownHostsRefreshTTL {
+		return nil
+	}
+
+	// The refresh outlives whichever caller started it, the calls it makes carry their own timeouts.
+	_, err, _ := t.hostsRefresh.Do("refresh", func() (any, error) {
+		err := t.resolveKnownHosts(context.WithoutCancel(ctx), r)
+		if err == nil {
+			t.hostsRefreshedAt.Store(time.Now().UnixNano())
+		}
+		return nil, err
+	})
+	return err
+}
+
+// resolveKnownHosts drops adapters that left the tailnet and asks new adapters who their host is.
+// Adapters behind a relay or adapter that didn't answer are kept until we hear otherwise.
+func (t *TSymbioteUIServer) resolveKnownHosts(ctx context.Context, r *tsymbiote.HTTPRequest) error {
+	listing, err := t.resolveAdapters(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	for _, known := range t.GetAdapters() {
+		if listing.gone(known) {
+			t.DeleteAdapter(known)
+		}
+	}
+
+	caller, err := t.newFanOutCaller(types.RequestOptions{}, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, adapter := range listing.Adapters {
+		if _, ok := t.GetHost(adapter); ok {
+			continue
+		}
+
+		wg.Go(func() {
+			_, _, err := t.callStatus(ctx, r, caller, adapter, nil)
+			if err != nil {
+				r.Log.Infow("failed to get status while refreshing hosts", "adapter", adapter, "error", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// callStatus calls Status on an adapter, decodes it into status when set and records the host the adapter serves.
+// Every caller that learns a host from an adapter goes through here so they agree on what the host is.
+// An adapter without a Self.HostName, IE: a logged out tailscaled, fails.
+func (t *TSymbioteUIServer) callStatus(ctx context.Context, r *tsymbiote.HTTPRequest, caller *fanOutCaller, adapter string, status any) (string, []types.Attempt, error) {
+	self := struct {
+		Self *struct {
+			HostName string
+		}
+	}{}
+	attempts, err := caller.CallAdapter(ctx, r, "POST", adapter, paths.Status.Adapter(), nil, func(resp io.Reader) error {
+		raw, err := io.ReadAll(resp)
+		if err != nil {
+			return err
+		}
+
+		err = json.Unmarshal(raw, &self)
+		if err != nil || status == nil {
+			return err
+		}
+		return json.Unmarshal(raw, status)
+	})
+	if err != nil {
+		return "", attempts, err
+	}
+
+	if self.Self == nil || self.Self.HostName == "" {
+		return "", attempts, errors.New("adapter status has no host name")
+	}
+
+	t.SetKnownHost(self.Self.HostName, adapter)
+	return self.Self.HostName, attempts, nil
+}
+
+// redialAdapterWebsocket retries dialing an adapter websocket with exponential backoff.
+// The host -> adapter mapping is refreshed before each attempt since restarted adapters come back with a new name.
+// onRetry is called before every wait so callers can surface progress to clients.
+func (t *TSymbioteUIServer) redialAdapterWebsocket(ctx context.Context, r *tsymbiote.HTTPRequest, host string, targetPath string, params url.Values, onRetry func(attempt int, delay time.Duration, lastErr error)) (*websocket.Conn, error) {
+	var lastErr error
+	for attempt := 1; attempt <= consts.WSReconnectMaxAttempts; attempt++ {
+		delay := utils.Backoff(attempt, consts.WSReconnectBaseDelay, consts.WSReconnectMaxDelay)
+		onRetry(attempt, delay, lastErr)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		err := t.refreshKnownHosts(ctx, r)
+		if err != nil {
+			r.Log.Infow("failed to refresh known hosts", "error", err)
+		}
+
+		conn, err := t.dialAdapterWebsocket(ctx, r, host, targetPath, params)
+		if err == nil {
+			return conn, nil
+		}
+
+		r.Log.Infow("failed to redial adapter", "host", host, "attempt", attempt, "error", err)
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("gave up reconnecting after %d attempts: %w", consts.WSReconnectMaxAttempts, lastErr)
+}
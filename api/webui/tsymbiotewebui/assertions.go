@@ -0,0 +1,83 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+)
+
+// AssertionsInput runs a set of checks, Hosts and Selector are used by checks that don't set their own.
+type AssertionsInput struct {
+	// Checks are runbook steps that must have an expect, IE:
+	// {"kind": "Prefs", "expect": "result.CorpDNS"}
+	Checks   []RunbookStep `json:"checks"`
+	Hosts    []string      `json:"hosts,omitempty"`
+	Selector string        `json:"selector,omitempty"`
+}
+
+// AssertionsReport is the outcome of every check, Passed is only true when every host passed every check.
+type AssertionsReport struct {
+	Passed bool                `json:"passed"`
+	Checks []RunbookStepReport `json:"checks"`
+}
+
+// Assertions runs each check against its endpoint and evaluates the expectation for every host.
+// Raw endpoint results are left out unless ?results=true is set.
+func (t *TSymbioteUIServer) Assertions(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &AssertionsInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode assertions input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	for i, check := range input.Checks {
+		err = t.validateRunbookStep(check)
+		if err == nil && check.Expect == "" {
+			err = errors.New("missing expect")
+		}
+		if err != nil {
+			r.Log.Errorw("invalid check", "check", i+1, "error", err)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	withResults := r.URL.Query().Get("results") == "true"
+	defaults := &RunbookRunInput{Hosts: input.Hosts, Selector: input.Selector}
+	// Checks run in parallel and share one inventory.
+	resolver := t.newHostResolver(r)
+
+	report := &AssertionsReport{
+		Passed: true,
+		Checks: make([]RunbookStepReport, len(input.Checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range input.Checks {
+		if check.Name == "" {
+			check.Name = check.Expect
+		}
+
+		wg.Go(func() {
+			checkReport := t.runRunbookStep(r, resolver, check, defaults)
+			if !withResults {
+				checkReport.Results = nil
+			}
+			report.Checks[i] = checkReport
+		})
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Error != "" || check.Failed > 0 {
+			report.Passed = false
+		}
+	}
+
+	t.WriteJson(w, r, report)
+}
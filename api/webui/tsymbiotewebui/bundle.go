@@ -0,0 +1,376 @@
+package tsymbiotewebui
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+)
+
+const (
+	defaultBundleSeconds        = 10
+	defaultBundleProfileSeconds = 5
+	maxBundleProfileSeconds     = 60
+)
+
+// bundleFiles are the adapter endpoints collected into every bundle, by file name.
+var bundleFiles = []struct {
+	name string
+	path paths.KnownPath
+}{
+	{"status.json", paths.Status},
+	{"prefs.json", paths.Prefs},
+	{"dnsconfig.json", paths.DNSConfig},
+	{"serveconfig.json", paths.ServeConfig},
+	{"appconnroutes.json", paths.AppConnRoutes},
+	{"driveshares.json", paths.DriveShares},
+	{"goroutines.txt", paths.Goroutines},
+}
+
+// BundleInput selects the hosts for an incident bundle and how long to capture for.
+type BundleInput struct {
+	types.RequestOptions
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// Seconds of logs and bus events to capture, defaults to 10.
+	Seconds int `json:"seconds,omitempty"`
+	// ProfileSeconds of CPU profile to take, defaults to 5, -1 skips the profile.
+	ProfileSeconds int `json:"profileSeconds,omitempty"`
+	// Redact replaces keys, emails, IPs, host names, MagicDNS names and tailnet names with placeholders
+	// in every text file, the manifest and the per host directory names.
+	Redact bool `json:"redact,omitempty"`
+}
+
+// BundleManifest is manifest.json at the root of the bundle.
+type BundleManifest struct {
+	TraceID        string       `json:"traceId"`
+	User           string       `json:"user,omitempty"`
+	Started        time.Time    `json:"started"`
+	Finished       time.Time    `json:"finished"`
+	Seconds        int          `json:"seconds"`
+	ProfileSeconds int          `json:"profileSeconds"`
+	Redacted       bool         `json:"redacted"`
+	Hosts          []BundleHost `json:"hosts"`
+}
+
+type BundleHost struct {
+	Host  string       `json:"host"`
+	Files []BundleFile `json:"files"`
+}
+
+// BundleFile is a single collected file, failed files are listed with their error and left out of the archive.
+type BundleFile struct {
+	Name     string          `json:"name"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Bytes    int             `json:"bytes"`
+	Error    string          `json:"error,omitempty"`
+	Attempts []types.Attempt `json:"attempts,omitempty"`
+
+	data []byte
+}
+
+// Bundle gathers diagnostics from every host into one .tar.gz, laid out as manifest.json and <host>/<file>.
+// Logs and bus events are captured while the other files are collected so the whole bundle takes about Seconds.
+func (t *TSymbioteUIServer) Bundle(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &BundleInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode bundle input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	if input.Seconds == 0 {
+		input.Seconds = defaultBundleSeconds
+	}
+	if input.ProfileSeconds == 0 {
+		input.ProfileSeconds = defaultBundleProfileSeconds
+	}
+	if input.Seconds < 0 || time.Duration(input.Seconds)*time.Second > maxRunbookCapture || input.ProfileSeconds > maxBundleProfileSeconds {
+		r.Log.Errorw("invalid bundle durations", "seconds", input.Seconds, "profileSeconds", input.ProfileSeconds)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, time.Duration(max(input.ProfileSeconds, 0))*time.Second)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	manifest := &BundleManifest{
+		TraceID:        r.TraceID,
+		User:           r.UserName,
+		Started:        time.Now().UTC(),
+		Seconds:        input.Seconds,
+		ProfileSeconds: input.ProfileSeconds,
+		Redacted:       input.Redact,
+		Hosts:          make([]BundleHost, len(input.Hosts)),
+	}
+
+	// Streams are captured for every host at once.
+	streams := map[paths.KnownPath][]runbookStreamResult{}
+	streamErrs := map[paths.KnownPath]error{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	streamStarted := time.Now().UTC()
+	for _, kind := range runbookStreamKinds {
+		wg.Go(func() {
+			raw, err := t.captureRunbookStream(r, kind, input.Hosts, RunbookStep{Duration: fmt.Sprintf("%ds", input.Seconds)})
+			results := []runbookStreamResult{}
+			if err == nil {
+				err = json.Unmarshal(raw, &results)
+			}
+
+			mu.Lock()
+			streams[kind] = results
+			if err != nil {
+				streamErrs[kind] = err
+			}
+			mu.Unlock()
+		})
+	}
+
+	for i, host := range input.Hosts {
+		manifest.Hosts[i].Host = host
+		wg.Go(func() {
+			manifest.Hosts[i].Files = t.collectBundleHost(r, caller, host, input.ProfileSeconds)
+		})
+	}
+	wg.Wait()
+	streamFinished := time.Now().UTC()
+
+	for i, host := range input.Hosts {
+		for _, kind := range runbookStreamKinds {
+			file := BundleFile{
+				Name:     fmt.Sprintf("%s.ndjson", strings.ToLower(kind.String())),
+				Started:  streamStarted,
+				Finished: streamFinished,
+			}
+			if err, ok := streamErrs[kind]; ok {
+				file.Error = err.Error()
+			}
+			for _, result := range streams[kind] {
+				if result.Host != host {
+					continue
+				}
+				file.Error = result.Error
+				file.data = []byte(strings.Join(result.Messages, "\n"))
+				if result.Truncated {
+					file.Error = fmt.Sprintf("truncated after %d messages", maxRunbookMessages)
+				}
+			}
+			manifest.Hosts[i].Files = append(manifest.Hosts[i].Files, file)
+		}
+	}
+
+	var redactor *utils.Redactor
+	if input.Redact {
+		redactor = utils.NewRedactor()
+	}
+	manifest.Finished = time.Now().UTC()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tsymbiote-bundle-%s.tar.gz"`, manifest.Started.Format("20060102T150405Z")))
+	err = writeBundle(w, manifest, redactor)
+	if err != nil {
+		// Headers are already sent, all we can do is log it.
+		r.Log.Errorw("failed to write bundle", "error", err)
+		return
+	}
+
+	r.Log.Infow("wrote incident bundle", "hosts", len(manifest.Hosts), "redacted", input.Redact)
+}
+
+// collectBundleHost calls every bundle endpoint on a single host in turn.
+func (t *TSymbioteUIServer) collectBundleHost(r *tsymbiote.HTTPRequest, caller *fanOutCaller, host string, profileSeconds int) []BundleFile {
+	collect := func(name string, knownPath paths.KnownPath, body []byte) BundleFile {
+		file := BundleFile{Name: name, Started: time.Now().UTC()}
+		attempts, err := caller.CallHost(r.Context(), r, "POST", host, knownPath.Adapter(), body, func(resp io.Reader) error {
+			var readErr error
+			file.data, readErr = io.ReadAll(resp)
+			return readErr
+		})
+		file.Finished = time.Now().UTC()
+		file.Attempts = attempts
+		if err != nil {
+			r.Log.Errorw("failed to collect bundle file", "host", host, "file", name, "error", err)
+			file.Error = err.Error()
+			file.data = nil
+		}
+		return file
+	}
+
+	var files []BundleFile
+	for _, bundleFile := range bundleFiles {
+		files = append(files, collect(bundleFile.name, bundleFile.path, nil))
+	}
+
+	if profileSeconds > 0 {
+		body, err := json.Marshal(&types.PprofInput{Type: "profile", Seconds: profileSeconds})
+		if err != nil {
+			files = append(files, BundleFile{Name: "cpu.pprof", Error: err.Error()})
+			return files
+		}
+		files = append(files, collect("cpu.pprof", paths.Pprof, body))
+	}
+
+	return files
+}
+
+// writeBundle writes the manifest followed by every collected file, binary profiles aren't redacted.
+func writeBundle(w io.Writer, manifest *BundleManifest, redactor *utils.Redactor) error {
+	type bundleEntry struct {
+		name    string
+		data    []byte
+		modTime time.Time
+	}
+
+	if redactor != nil {
+		addBundleNames(redactor, manifest)
+	}
+
+	var entries []bundleEntry
+	for i, host := range manifest.Hosts {
+		dir := host.Host
+		if redactor != nil {
+			dir = strings.Trim(redactor.Placeholder("host", host.Host), "<>")
+		}
+
+		for j, file := range host.Files {
+			if file.Error != "" && file.data == nil {
+				continue
+			}
+
+			data := file.data
+			if redactor != nil && path.Ext(file.Name) != ".pprof" {
+				data = redactor.Redact(data)
+			}
+			manifest.Hosts[i].Files[j].Bytes = len(data)
+
+			entries = append(entries, bundleEntry{
+				name:    path.Join(bundleDir(dir), file.Name),
+				data:    data,
+				modTime: file.Finished,
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if redactor != nil {
+		data = redactor.Redact(data)
+	}
+	entries = append([]bundleEntry{{name: "manifest.json", data: data, modTime: manifest.Finished}}, entries...)
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	for _, entry := range entries {
+		err = archive.WriteHeader(&tar.Header{
+			Name:    entry.name,
+			Mode:    0644,
+			Size:    int64(len(entry.data)),
+			ModTime: entry.modTime,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = archive.Write(entry.data)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// bundleStatus is the part of a Status the redactor needs names from.
+type bundleStatus struct {
+	MagicDNSSuffix string
+	CurrentTailnet *struct {
+		Name           string
+		MagicDNSSuffix string
+	}
+	Self *bundleStatusNode
+	Peer map[string]*bundleStatusNode
+}
+
+type bundleStatusNode struct {
+	HostName string
+	DNSName  string
+}
+
+// addBundleNames registers every host, MagicDNS and tailnet name in the bundle with the redactor before anything is redacted,
+// names are only known from status.json but appear in every other file.
+func addBundleNames(redactor *utils.Redactor, manifest *BundleManifest) {
+	for _, host := range manifest.Hosts {
+		redactor.AddNames("host", host.Host)
+	}
+
+	for _, host := range manifest.Hosts {
+		for _, file := range host.Files {
+			if file.Name != "status.json" || file.data == nil {
+				continue
+			}
+
+			status := &bundleStatus{}
+			if json.Unmarshal(file.data, status) != nil {
+				continue
+			}
+
+			nodes := []*bundleStatusNode{status.Self}
+			for _, peer := range status.Peer {
+				nodes = append(nodes, peer)
+			}
+			for _, node := range nodes {
+				if node == nil {
+					continue
+				}
+				redactor.AddNames("dns", node.DNSName)
+				redactor.AddNames("host", node.HostName)
+			}
+
+			redactor.AddNames("tailnet", status.MagicDNSSuffix)
+			if status.CurrentTailnet != nil {
+				redactor.AddNames("tailnet", status.CurrentTailnet.MagicDNSSuffix, status.CurrentTailnet.Name)
+			}
+		}
+	}
+}
+
+// bundleDir keeps host names from escaping the bundle root.
+func bundleDir(host string) string {
+	dir := strings.NewReplacer("/", "_", "\\", "_").Replace(host)
+	if dir == "" || dir == "." || dir == ".." {
+		return "_"
+	}
+	return dir
+}
@@ -0,0 +1,56 @@
+package tsymbiotewebui
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("writeBundle", func() {
+	It("Should redact host, MagicDNS and tailnet names everywhere including directory names", func() {
+		status := `{"MagicDNSSuffix":"tail1234.ts.net","Self":{"HostName":"web-1","DNSName":"web-1.tail1234.ts.net."},"Peer":{"nodekey:abc":{"HostName":"db-1","DNSName":"db-1.tail1234.ts.net."}}}`
+		manifest := &BundleManifest{
+			Redacted: true,
+			Hosts: []BundleHost{{
+				Host: "web-1",
+				Files: []BundleFile{
+					{Name: "status.json", data: []byte(status)},
+					{Name: "logs.ndjson", data: []byte(`dialing db-1.tail1234.ts.net from web-1`)},
+				},
+			}},
+		}
+
+		out := &bytes.Buffer{}
+		Expect(writeBundle(out, manifest, utils.NewRedactor())).To(Succeed())
+
+		gz, err := gzip.NewReader(out)
+		Expect(err).NotTo(HaveOccurred())
+		archive := tar.NewReader(gz)
+
+		files := map[string]string{}
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).NotTo(HaveOccurred())
+			data, err := io.ReadAll(archive)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(data)
+		}
+
+		Expect(files).To(HaveKey("manifest.json"))
+		Expect(files).To(HaveKey("host-1/status.json"))
+		Expect(files["host-1/logs.ndjson"]).To(Equal(`dialing <dns-2> from <host-1>`))
+		for name, data := range files {
+			Expect(data).NotTo(ContainSubstring("web-1"), name)
+			Expect(data).NotTo(ContainSubstring("db-1"), name)
+			Expect(data).NotTo(ContainSubstring("tail1234"), name)
+		}
+	})
+})
@@ -0,0 +1,471 @@
+package tsymbiotewebui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// Check states, a check starts unknown until its first run.
+const (
+	CheckUnknown = "unknown"
+	CheckPassing = "passing"
+	CheckFailing = "failing"
+)
+
+// Webhook payload formats.
+const (
+	WebhookGeneric = "generic"
+	WebhookSlack   = "slack"
+)
+
+const (
+	webhookTimeout  = time.Second * 10
+	webhookAttempts = 3
+	checkUser       = "tsymbiote-scheduler"
+)
+
+// ScheduleConfig is loaded from the file given with --schedule-file.
+//
+//	webhooks:
+//	  - name: oncall
+//	    url: https://hooks.slack.com/services/...
+//	    format: slack
+//	checks:
+//	  - name: prod-dns
+//	    schedule: "*/5 * * * *"
+//	    kind: QueryDNS
+//	    selector: tag:prod
+//	    params: {name: db.internal, queryType: A}
+//	    expect: '"10.0.0.5" in result.responses'
+type ScheduleConfig struct {
+	Webhooks []Webhook       `json:"webhooks,omitempty"`
+	Checks   []ScheduleCheck `json:"checks"`
+}
+
+// Webhook is an endpoint alerts are posted to.
+type Webhook struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Format string `json:"format,omitempty"`
+	// Template overrides the body with a text/template executed against CheckAlert.
+	Template string            `json:"template,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+
+	template *template.Template
+}
+
+// ScheduleCheck is a runbook step run on a standard five field cron schedule, descriptors like @hourly or @every 5m also work.
+// Checks without an expect only fail when a host returns an error.
+type ScheduleCheck struct {
+	RunbookStep `json:",inline"`
+	Schedule    string `json:"schedule"`
+	// Webhooks to alert by name, all webhooks are used when empty.
+	Webhooks []string `json:"webhooks,omitempty"`
+}
+
+// CheckAlert is sent to webhooks when a check changes state.
+type CheckAlert struct {
+	Check    string                    `json:"check"`
+	Kind     string                    `json:"kind"`
+	State    string                    `json:"state"`
+	Previous string                    `json:"previous"`
+	Time     time.Time                 `json:"time"`
+	TraceID  string                    `json:"traceId"`
+	Hosts    int                       `json:"hosts"`
+	Passed   int                       `json:"passed"`
+	Failed   int                       `json:"failed"`
+	Error    string                    `json:"error,omitempty"`
+	Failures []types.ExpectationResult `json:"failures,omitempty"`
+}
+
+// CheckStatus is the current view of a scheduled check.
+type CheckStatus struct {
+	Name     string             `json:"name"`
+	Kind     string             `json:"kind"`
+	Schedule string             `json:"schedule"`
+	State    string             `json:"state"`
+	Changed  time.Time          `json:"changed,omitzero"`
+	LastRun  time.Time          `json:"lastRun,omitzero"`
+	NextRun  time.Time          `json:"nextRun,omitzero"`
+	Last     *RunbookStepReport `json:"last,omitempty"`
+}
+
+type scheduledCheck struct {
+	ScheduleCheck
+	schedule cron.Schedule
+
+	mu      sync.Mutex
+	running bool
+	status  CheckStatus
+}
+
+// scheduler runs checks in the background and alerts webhooks on state changes.
+type scheduler struct {
+	*TSymbioteUIServer
+	webhooks []*Webhook
+	checks   []*scheduledCheck
+	client   *http.Client
+}
+
+// loadSchedule parses and validates the schedule file.
+func (t *TSymbioteUIServer) loadSchedule(path string) (*scheduler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ScheduleConfig{}
+	err = yaml.UnmarshalStrict(data, config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scheduler{
+		TSymbioteUIServer: t,
+		client:            &http.Client{Timeout: webhookTimeout},
+	}
+
+	for _, webhook := range config.Webhooks {
+		if webhook.Name == "" || webhook.URL == "" {
+			return nil, errors.New("webhooks need a name and url")
+		}
+
+		switch webhook.Format {
+		case "":
+			webhook.Format = WebhookGeneric
+		case WebhookGeneric, WebhookSlack:
+		default:
+			return nil, fmt.Errorf("webhook %s: unknown format: %q", webhook.Name, webhook.Format)
+		}
+
+		if webhook.Template != "" {
+			webhook.template, err = template.New(webhook.Name).Parse(webhook.Template)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: invalid template: %w", webhook.Name, err)
+			}
+		}
+		s.webhooks = append(s.webhooks, &webhook)
+	}
+
+	for _, check := range config.Checks {
+		if check.Name == "" || slices.ContainsFunc(s.checks, func(existing *scheduledCheck) bool { return existing.Name == check.Name }) {
+			return nil, fmt.Errorf("checks need a unique name: %q", check.Name)
+		}
+
+		if check.Expect == "" {
+			check.Expect = "true"
+		}
+
+		err = t.validateRunbookStep(check.RunbookStep)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", check.Name, err)
+		}
+
+		schedule, err := cron.ParseStandard(check.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", check.Name, err)
+		}
+
+		for _, name := range check.Webhooks {
+			if !slices.ContainsFunc(s.webhooks, func(webhook *Webhook) bool { return webhook.Name == name }) {
+				return nil, fmt.Errorf("check %s: unknown webhook: %q", check.Name, name)
+			}
+		}
+
+		s.checks = append(s.checks, &scheduledCheck{
+			ScheduleCheck: check,
+			schedule:      schedule,
+			status: CheckStatus{
+				Name:     check.Name,
+				Kind:     check.Kind,
+				Schedule: check.Schedule,
+				State:    CheckUnknown,
+			},
+		})
+	}
+
+	return s, nil
+}
+
+// run fires checks as they come due until shutdown, a check still running from its last slot is skipped.
+func (s *scheduler) run(shutdownCtx context.Context) {
+	s.Log.Infow("starting check scheduler", "checks", len(s.checks), "webhooks", len(s.webhooks))
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	now := time.Now()
+	for _, check := range s.checks {
+		check.mu.Lock()
+		check.status.NextRun = check.schedule.Next(now)
+		check.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case now := <-ticker.C:
+			for _, check := range s.checks {
+				check.mu.Lock()
+				due := !check.status.NextRun.IsZero() && !now.Before(check.status.NextRun)
+				if due {
+					check.status.NextRun = check.schedule.Next(now)
+				}
+				skip := due && check.running
+				if due && !skip {
+					check.running = true
+				}
+				check.mu.Unlock()
+
+				if skip {
+					s.Log.Infow("skipping check, previous run still going", "check", check.Name)
+				}
+				if !due || skip {
+					continue
+				}
+
+				wg.Go(func() {
+					s.runCheck(shutdownCtx, check)
+				})
+			}
+		}
+	}
+}
+
+// runCheck runs a check once and alerts if its state changed, callers must set running.
+func (s *scheduler) runCheck(ctx context.Context, check *scheduledCheck) {
+	defer func() {
+		check.mu.Lock()
+		check.running = false
+		check.mu.Unlock()
+	}()
+
+	r, err := s.backgroundRequest(ctx, checkUser)
+	if err != nil {
+		s.Log.Errorw("failed to build check request", "check", check.Name, "error", err)
+		return
+	}
+	r.Log = r.Log.With(zap.String("check", check.Name))
+	log := r.Log
+
+	report := s.runRunbookStep(r, s.newHostResolver(r), check.RunbookStep, &RunbookRunInput{})
+	// Shutting down mid run isn't a failure.
+	if ctx.Err() != nil {
+		return
+	}
+
+	state := CheckPassing
+	if report.Error != "" || report.Failed > 0 {
+		state = CheckFailing
+	}
+
+	check.mu.Lock()
+	previous := check.status.State
+	check.status.State = state
+	check.status.LastRun = report.Started
+	check.status.Last = &report
+	if previous != state {
+		check.status.Changed = report.Started
+	}
+	check.mu.Unlock()
+
+	log.Infow("check finished", "state", state, "passed", report.Passed, "failed", report.Failed, "error", report.Error)
+
+	// The first passing run is the expected state, don't page anyone for it.
+	if previous == state || (previous == CheckUnknown && state == CheckPassing) {
+		return
+	}
+
+	alert := CheckAlert{
+		Check:    check.Name,
+		Kind:     check.Kind,
+		State:    state,
+		Previous: previous,
+		Time:     report.Started,
+		TraceID:  r.TraceID,
+		Hosts:    len(report.Hosts),
+		Passed:   report.Passed,
+		Failed:   report.Failed,
+		Error:    report.Error,
+	}
+	for _, result := range report.Expectations {
+		if !result.Passed {
+			alert.Failures = append(alert.Failures, result)
+		}
+	}
+
+	for _, webhook := range s.webhooks {
+		if len(check.Webhooks) > 0 && !slices.Contains(check.Webhooks, webhook.Name) {
+			continue
+		}
+
+		err = s.sendAlert(ctx, webhook, alert)
+		if err != nil {
+			log.Errorw("failed to send alert", "webhook", webhook.Name, "error", err)
+		}
+	}
+}
+
+// alertBody renders the alert in the webhook format, a template always wins.
+func (w *Webhook) alertBody(alert CheckAlert) ([]byte, error) {
+	if w.template != nil {
+		body := &bytes.Buffer{}
+		err := w.template.Execute(body, alert)
+		return body.Bytes(), err
+	}
+
+	if w.Format != WebhookSlack {
+		return json.Marshal(alert)
+	}
+
+	icon := ":white_check_mark:"
+	if alert.State == CheckFailing {
+		icon = ":rotating_light:"
+	}
+
+	text := &strings.Builder{}
+	fmt.Fprintf(text, "%s *%s* is %s (was %s)\n", icon, alert.Check, alert.State, alert.Previous)
+	fmt.Fprintf(text, "%s on %d hosts: %d passed, %d failed", alert.Kind, alert.Hosts, alert.Passed, alert.Failed)
+	if alert.Error != "" {
+		fmt.Fprintf(text, "\nError: %s", alert.Error)
+	}
+	for i, failure := range alert.Failures {
+		// Keep the message readable, the rest is in the WebUI.
+		if i == 10 {
+			fmt.Fprintf(text, "\n...and %d more", len(alert.Failures)-i)
+			break
+		}
+
+		detail := failure.Error
+		if detail == "" {
+			value, _ := json.Marshal(failure.Value)
+			detail = string(value)
+		}
+		fmt.Fprintf(text, "\n• `%s`: %s", failure.Host, detail)
+	}
+	fmt.Fprintf(text, "\ntrace-id: %s", alert.TraceID)
+
+	return json.Marshal(map[string]string{"text": text.String()})
+}
+
+// sendAlert posts the alert, retrying server errors with backoff.
+func (s *scheduler) sendAlert(ctx context.Context, webhook *Webhook, alert CheckAlert) error {
+	body, err := webhook.alertBody(alert)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = s.postWebhook(ctx, webhook, body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(utils.Backoff(attempt, time.Second, webhookTimeout))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *scheduler) postWebhook(ctx context.Context, webhook *Webhook, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for header, value := range webhook.Headers {
+		request.Header.Set(header, value)
+	}
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *scheduler) find(name string) (*scheduledCheck, bool) {
+	for _, check := range s.checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return nil, false
+}
+
+// Checks lists every scheduled check with its current state and last result.
+func (t *TSymbioteUIServer) Checks(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	statuses := []CheckStatus{}
+	if t.scheduler != nil {
+		for _, check := range t.scheduler.checks {
+			check.mu.Lock()
+			statuses = append(statuses, check.status)
+			check.mu.Unlock()
+		}
+	}
+
+	t.WriteJson(w, r, statuses)
+}
+
+// RunCheck runs a scheduled check now instead of waiting on its schedule, state changes still alert.
+func (t *TSymbioteUIServer) RunCheck(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	if t.scheduler == nil {
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	check, ok := t.scheduler.find(r.PathValue("name"))
+	if !ok {
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	check.mu.Lock()
+	running := check.running
+	check.running = true
+	check.mu.Unlock()
+
+	if running {
+		r.Log.Infow("check already running", "check", check.Name)
+		r.SetStatusCode(w, http.StatusConflict)
+		return
+	}
+
+	r.Log.Infow("running check on demand", "check", check.Name)
+	t.RunWSFunc(func(shutdownCtx context.Context) {
+		t.scheduler.runCheck(shutdownCtx, check)
+	})
+
+	r.SetStatusCode(w, http.StatusAccepted)
+}
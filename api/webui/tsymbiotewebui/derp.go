@@ -0,0 +1,218 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+// derpProbeTimeout is added on top of the request timeout, a full DERP map takes a while to check.
+const derpProbeTimeout = time.Minute * 2
+
+func (t *TSymbioteUIServer) BugReport(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+	input := &types.BugReportInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode bugreport input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(&types.BugReportInput{Note: input.Note, Diagnose: input.Diagnose})
+	if err != nil {
+		r.Log.Errorw("failed to marshal bugreport input", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	var channels []chan types.BugReportResult
+	for _, targetHost := range input.Hosts {
+
+		ch := make(chan types.BugReportResult)
+		channels = append(channels, ch)
+
+		go func() {
+			result := types.BugReportResult{}
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.BugReport.Adapter(), body, func(resp io.Reader) error {
+				return json.NewDecoder(resp).Decode(&result)
+			})
+			result.Host = targetHost
+			result.Attempts = attempts
+			if err != nil {
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
+				result.Error = err.Error()
+			}
+
+			ch <- result
+		}()
+	}
+
+	writeFanOut(t, w, r, channels, func(res types.BugReportResult) string { return res.Error })
+}
+
+// DERPRegion is a column of the check table.
+type DERPRegion struct {
+	ID   int    `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
+// DERPLatency is a cell of the check table, see types.DERPRegionProbe.
+type DERPLatency struct {
+	V4 time.Duration `json:"v4,omitempty"`
+	V6 time.Duration `json:"v6,omitempty"`
+}
+
+// fastest is the lower of the two latencies, zero when neither family reached the region.
+func (l DERPLatency) fastest() time.Duration {
+	if l.V4 == 0 || (l.V6 != 0 && l.V6 < l.V4) {
+		return l.V6
+	}
+	return l.V4
+}
+
+// DERPHostCheck is a row of the check table, regions are keyed by code.
+type DERPHostCheck struct {
+	Host  string `json:"host"`
+	Error string `json:"error,omitempty"`
+	// Latency is each region's netcheck latency, Closest is the region with the lowest.
+	Latency map[string]DERPLatency `json:"latency"`
+	Closest string                 `json:"closest,omitempty"`
+	// CheckDuration is how long each region's check took, see types.DERPRegionProbe.
+	CheckDuration map[string]time.Duration         `json:"checkDuration"`
+	Problems      map[string][]string              `json:"problems,omitempty"`
+	Probes        map[string]types.DERPRegionProbe `json:"probes,omitempty"`
+	Attempts      []types.Attempt                  `json:"attempts,omitempty"`
+}
+
+// DERPCheckTable is every host's check of every probed DERP region.
+type DERPCheckTable struct {
+	Regions []DERPRegion    `json:"regions"`
+	Hosts   []DERPHostCheck `json:"hosts"`
+}
+
+// DERPRegions runs the debug DERP region check from every host and builds a host x region table of netcheck latencies,
+// with each check's duration and problems alongside.
+// Full check details are only included with ?details=true.
+func (t *TSymbioteUIServer) DERPRegions(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+	input := &types.DERPProbeInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode derp probe input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, derpProbeTimeout)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(&types.DERPProbeInput{Regions: input.Regions})
+	if err != nil {
+		r.Log.Errorw("failed to marshal derp probe input", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	details := r.URL.Query().Get("details") == "true"
+	table := &DERPCheckTable{
+		Regions: []DERPRegion{},
+		Hosts:   make([]DERPHostCheck, len(input.Hosts)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, targetHost := range input.Hosts {
+		wg.Go(func() {
+			row := DERPHostCheck{
+				Host:          targetHost,
+				Latency:       map[string]DERPLatency{},
+				CheckDuration: map[string]time.Duration{},
+				Problems:      map[string][]string{},
+			}
+
+			var probes []types.DERPRegionProbe
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.DERPRegions.Adapter(), body, func(resp io.Reader) error {
+				probes = []types.DERPRegionProbe{}
+				return json.NewDecoder(resp).Decode(&probes)
+			})
+			row.Attempts = attempts
+			if err != nil {
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
+				row.Error = err.Error()
+			}
+
+			if details {
+				row.Probes = map[string]types.DERPRegionProbe{}
+			}
+			var closest time.Duration
+			for _, probe := range probes {
+				latency := DERPLatency{V4: probe.LatencyV4, V6: probe.LatencyV6}
+				row.Latency[probe.RegionCode] = latency
+				if fastest := latency.fastest(); fastest != 0 && (closest == 0 || fastest < closest) {
+					closest = fastest
+					row.Closest = probe.RegionCode
+				}
+
+				row.CheckDuration[probe.RegionCode] = probe.CheckDuration
+				if details {
+					row.Probes[probe.RegionCode] = probe
+				}
+
+				problems := append(slices.Clone(probe.Errors), probe.Warnings...)
+				if len(problems) > 0 {
+					row.Problems[probe.RegionCode] = problems
+				}
+
+				mu.Lock()
+				if !slices.ContainsFunc(table.Regions, func(region DERPRegion) bool { return region.ID == probe.RegionID }) {
+					table.Regions = append(table.Regions, DERPRegion{ID: probe.RegionID, Code: probe.RegionCode, Name: probe.RegionName})
+				}
+				mu.Unlock()
+			}
+
+			table.Hosts[i] = row
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(table.Regions, func(a, b DERPRegion) int {
+		return a.ID - b.ID
+	})
+
+	t.WriteJson(w, r, table)
+}
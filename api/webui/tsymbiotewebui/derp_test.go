@@ -0,0 +1,17 @@
+package tsymbiotewebui
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DERPLatency", func() {
+	It("Should use whichever address family reached the region fastest", func() {
+		Expect(DERPLatency{V4: time.Millisecond * 20, V6: time.Millisecond * 10}.fastest()).To(Equal(time.Millisecond * 10))
+		Expect(DERPLatency{V4: time.Millisecond * 20}.fastest()).To(Equal(time.Millisecond * 20))
+		Expect(DERPLatency{V6: time.Millisecond * 30}.fastest()).To(Equal(time.Millisecond * 30))
+		Expect(DERPLatency{}.fastest()).To(BeZero())
+	})
+})
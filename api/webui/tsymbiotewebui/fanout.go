@@ -0,0 +1,138 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+)
+
+// Streaming modes for fan-out responses, selected with ?stream= or the Accept header.
+const (
+	fanOutNDJSON = "ndjson"
+	fanOutSSE    = "sse"
+)
+
+// Record types written in streaming mode.
+const (
+	FanOutRecordResult  = "result"
+	FanOutRecordSummary = "summary"
+)
+
+// FanOutRecord wraps every line written in streaming mode so the summary can be told apart from results.
+type FanOutRecord struct {
+	Type    string         `json:"type"`
+	Result  any            `json:"result,omitempty"`
+	Summary *FanOutSummary `json:"summary,omitempty"`
+}
+
+// FanOutSummary is always the final record of a streamed response.
+type FanOutSummary struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// fanOutMode returns the requested streaming mode, empty means a single JSON array like before.
+func fanOutMode(r *tsymbiote.HTTPRequest) string {
+	switch strings.ToLower(r.URL.Query().Get("stream")) {
+	case fanOutNDJSON:
+		return fanOutNDJSON
+	case fanOutSSE:
+		return fanOutSSE
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "application/x-ndjson"):
+		return fanOutNDJSON
+	case strings.Contains(accept, "text/event-stream"):
+		return fanOutSSE
+	}
+	return ""
+}
+
+// writeFanOut writes the per host results of a fan-out request.
+// By default results are collected in request order and written as one JSON array.
+// In streaming mode each result is flushed as soon as it completes, followed by a summary record.
+// resultError returns the error string of a result, used for the summary.
+func writeFanOut[T any](t *TSymbioteUIServer, w http.ResponseWriter, r *tsymbiote.HTTPRequest, channels []chan T, resultError func(T) string) {
+	mode := fanOutMode(r)
+	if mode == "" {
+		results := make([]T, 0, len(channels))
+		for _, channel := range channels {
+			res := <-channel
+			close(channel)
+			results = append(results, res)
+		}
+
+		t.WriteJson(w, r, results)
+		return
+	}
+
+	start := time.Now()
+
+	// Merge into completion order.
+	merged := make(chan T, len(channels))
+	for _, channel := range channels {
+		go func() {
+			res := <-channel
+			close(channel)
+			merged <- res
+		}()
+	}
+
+	if mode == fanOutSSE {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	controller := http.NewResponseController(w)
+	writeRecord := func(record FanOutRecord) error {
+		body, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		if mode == fanOutSSE {
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", record.Type, body)
+		} else {
+			_, err = fmt.Fprintf(w, "%s\n", body)
+		}
+		if err != nil {
+			return err
+		}
+
+		return controller.Flush()
+	}
+
+	summary := &FanOutSummary{Total: len(channels)}
+	for range channels {
+		res := <-merged
+		if resultError(res) != "" {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+
+		err := writeRecord(FanOutRecord{Type: FanOutRecordResult, Result: res})
+		if err != nil {
+			// Client went away, the remaining goroutines still drain into the buffered channel.
+			r.Log.Errorw("failed to write streamed result", "error", err)
+			return
+		}
+	}
+
+	summary.Elapsed = time.Since(start)
+	err := writeRecord(FanOutRecord{Type: FanOutRecordSummary, Summary: summary})
+	if err != nil {
+		r.Log.Errorw("failed to write streamed summary", "error", err)
+	}
+}
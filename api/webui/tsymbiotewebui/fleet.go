@@ -0,0 +1,133 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/ipn/ipnstate"
+)
+
+// fleetHost is everything a single adapter reported for a fleet wide report.
+type fleetHost struct {
+	Host    string
+	Adapter string
+	Tags    []string
+	Status  *ipnstate.Status
+	// Error is set when any call to the adapter failed, the other fields may be partial.
+	Error string
+
+	results map[paths.KnownPath]json.RawMessage
+}
+
+// Decode reads the response of an extra path requested with collectFleet.
+func (f *fleetHost) Decode(path paths.KnownPath, v any) error {
+	raw, ok := f.results[path]
+	if !ok {
+		return fmt.Errorf("no %s result", path)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// collectFleet calls Status, and any extra paths, on every adapter matching the selector.
+// Request options come from query params like PeerMap. Hosts are sorted by name.
+func (t *TSymbioteUIServer) collectFleet(ctx context.Context, r *tsymbiote.HTTPRequest, selector string, extra ...paths.KnownPath) ([]*fleetHost, error) {
+	var parsed *types.HostSelector
+	if selector != "" {
+		var err error
+		parsed, err = types.ParseHostSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidSelector, err)
+		}
+	}
+
+	opts, err := requestOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidOptions, err)
+	}
+
+	caller, err := t.newFanOutCaller(opts, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidOptions, err)
+	}
+
+	adapters, hostDevices, err := t.listAdapters(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	fleet := []*fleetHost{}
+	for _, adapter := range adapters {
+		wg.Go(func() {
+			host := &fleetHost{
+				Adapter: adapter,
+				results: map[paths.KnownPath]json.RawMessage{},
+			}
+
+			host.Status = &ipnstate.Status{}
+			hostName, _, err := t.callStatus(ctx, r, caller, adapter, host.Status)
+			if err != nil {
+				r.Log.Errorw("failed to get status for fleet", "adapter", adapter, "error", err)
+				// Without Status we don't know the host, it can't be selected either.
+				if parsed == nil {
+					host.Host = adapter
+					host.Status = nil
+					host.Error = fmt.Sprintf("failed to get status: %v", err)
+					mu.Lock()
+					fleet = append(fleet, host)
+					mu.Unlock()
+				}
+				return
+			}
+
+			host.Host = hostName
+			if device, ok := hostDevices[host.Host]; ok {
+				host.Tags = device.Tags
+			}
+
+			if parsed != nil && !parsed.Match(types.HostInfo{
+				Host:    host.Host,
+				Adapter: host.Adapter,
+				OS:      host.Status.Self.OS,
+				Tags:    host.Tags,
+				Online:  host.Status.Self.Online,
+			}) {
+				return
+			}
+
+			var errs []string
+			for _, path := range extra {
+				_, err := caller.CallAdapter(ctx, r, "POST", adapter, path.Adapter(), nil, func(resp io.Reader) error {
+					raw, err := io.ReadAll(resp)
+					host.results[path] = raw
+					return err
+				})
+				if err != nil {
+					r.Log.Errorw("failed to call adapter for fleet", "adapter", adapter, "path", path, "error", err)
+					errs = append(errs, fmt.Sprintf("failed to get %s: %v", path, err))
+				}
+			}
+			host.Error = strings.Join(errs, ", ")
+
+			mu.Lock()
+			fleet = append(fleet, host)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(fleet, func(a, b *fleetHost) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+	return fleet, nil
+}
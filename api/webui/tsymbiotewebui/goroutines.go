@@ -1,25 +1,29 @@
 package tsymbiotewebui
 
 import (
-	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"time"
 
 	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
 )
 
 type goroutinesInput struct {
+	types.RequestOptions
 	Hosts []string `json:"hosts"`
+	// Selector picks hosts by expression in addition to Hosts, IE: tag:prod os:linux
+	Selector string `json:"selector,omitempty"`
 }
 
 type goroutinesResult struct {
 	Host   string `json:"host,omitempty"`
 	Error  string `json:"error,omitempty"`
 	Result []byte `json:"result,omitempty"`
+	// Attempts made against the adapter, more than one when retried.
+	Attempts []types.Attempt `json:"attempts,omitempty"`
 }
 
 func (t *TSymbioteUIServer) Goroutines(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
@@ -32,8 +36,19 @@ func (t *TSymbioteUIServer) Goroutines(w http.ResponseWriter, r *tsymbiote.HTTPR
 		return
 	}
 
-	outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(consts.OutgoingRequestTimeout))
-	defer outgoingcancel()
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
 
 	var channels []chan goroutinesResult
 	for _, targetHost := range input.Hosts {
@@ -45,33 +60,20 @@ func (t *TSymbioteUIServer) Goroutines(w http.ResponseWriter, r *tsymbiote.HTTPR
 			result := goroutinesResult{}
 			result.Host = targetHost
 
-			resp, err := t.CallHost(outgoingctx, r, "POST", targetHost, paths.Goroutines.Adapter(), nil)
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.Goroutines.Adapter(), nil, func(resp io.Reader) error {
+				callRes, err := io.ReadAll(resp)
+				result.Result = callRes
+				return err
+			})
+			result.Attempts = attempts
 			if err != nil {
-				r.Log.Errorw("failed to call adapter", "error", err)
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
 				result.Error = err.Error()
-				ch <- result
-				return
 			}
 
-			defer resp.Close()
-
-			callRes, err := io.ReadAll(resp)
-			if err != nil {
-				r.Log.Errorw("failed to read response body", "error", err)
-				result.Error = err.Error()
-			}
-
-			result.Result = callRes
 			ch <- result
 		}()
 	}
 
-	goroutinesResults := []goroutinesResult{}
-	for _, channel := range channels {
-		res := <-channel
-		close(channel)
-		goroutinesResults = append(goroutinesResults, res)
-	}
-
-	t.WriteJson(w, r, goroutinesResults)
+	writeFanOut(t, w, r, channels, func(res goroutinesResult) string { return res.Error })
 }
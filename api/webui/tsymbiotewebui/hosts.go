@@ -0,0 +1,162 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+var errInvalidSelector = errors.New("invalid host selector")
+
+// hostInventory builds the selector view of every host with a reachable adapter.
+// Name, OS and online state come from the adapter's Status.Self, tags come from the host's device.
+func (t *TSymbioteUIServer) hostInventory(ctx context.Context, r *tsymbiote.HTTPRequest) ([]types.HostInfo, error) {
+	adapters, hostDevices, err := t.listAdapters(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := t.newFanOutCaller(types.RequestOptions{}, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	inventory := []types.HostInfo{}
+	for _, adapter := range adapters {
+		wg.Go(func() {
+			status := struct {
+				Self struct {
+					OS     string
+					Online bool
+				}
+			}{}
+
+			host, _, err := t.callStatus(ctx, r, caller, adapter, &status)
+			if err != nil {
+				// Hosts we can't reach can't be targeted either, leave them out.
+				r.Log.Infow("failed to get status for host inventory", "adapter", adapter, "error", err)
+				return
+			}
+
+			info := types.HostInfo{
+				Host:    host,
+				Adapter: adapter,
+				OS:      status.Self.OS,
+				Online:  status.Self.Online,
+			}
+			if device, ok := hostDevices[info.Host]; ok {
+				info.Tags = device.Tags
+				if info.OS == "" {
+					info.OS = device.OS
+				}
+			}
+
+			mu.Lock()
+			inventory = append(inventory, info)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(inventory, func(a, b types.HostInfo) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+	return inventory, nil
+}
+
+// hostResolver expands host selectors for a single request, the inventory is only fetched once.
+type hostResolver struct {
+	*TSymbioteUIServer
+	r *tsymbiote.HTTPRequest
+
+	mu        sync.Mutex
+	inventory []types.HostInfo
+}
+
+func (t *TSymbioteUIServer) newHostResolver(r *tsymbiote.HTTPRequest) *hostResolver {
+	return &hostResolver{TSymbioteUIServer: t, r: r}
+}
+
+// Resolve returns the explicit hosts followed by any selected hosts that weren't already listed.
+func (h *hostResolver) Resolve(ctx context.Context, hosts []string, selector string) ([]string, error) {
+	if selector == "" {
+		return hosts, nil
+	}
+
+	parsed, err := types.ParseHostSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidSelector, err)
+	}
+
+	h.mu.Lock()
+	if h.inventory == nil {
+		h.inventory, err = h.hostInventory(ctx, h.r)
+	}
+	inventory := h.inventory
+	h.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := slices.Clone(hosts)
+	for _, host := range parsed.Select(inventory) {
+		if !slices.Contains(resolved, host) {
+			resolved = append(resolved, host)
+		}
+	}
+
+	h.r.Log.Infow("resolved host selector", "selector", selector, "hosts", len(resolved))
+	return resolved, nil
+}
+
+// resolveHosts is a shorthand for handlers with a single hosts list.
+func (t *TSymbioteUIServer) resolveHosts(r *tsymbiote.HTTPRequest, hosts []string, selector string) ([]string, error) {
+	return t.newHostResolver(r).Resolve(r.Context(), hosts, selector)
+}
+
+// resolveStatus is the status code returned when resolving hosts or collecting the fleet fails.
+func resolveStatus(err error) int {
+	if errors.Is(err, errInvalidSelector) || errors.Is(err, errInvalidOptions) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Hosts lists every targetable host, optionally filtered with ?selector= to preview what a selector picks.
+func (t *TSymbioteUIServer) Hosts(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	var selector *types.HostSelector
+	if raw := r.URL.Query().Get("selector"); raw != "" {
+		parsed, err := types.ParseHostSelector(raw)
+		if err != nil {
+			r.Log.Errorw("invalid host selector", "error", err)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+		selector = parsed
+	}
+
+	inventory, err := t.hostInventory(r.Context(), r)
+	if err != nil {
+		r.Log.Errorw("failed to build host inventory", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	if selector != nil {
+		inventory = slices.DeleteFunc(inventory, func(host types.HostInfo) bool {
+			return !selector.Match(host)
+		})
+	}
+
+	t.WriteJson(w, r, inventory)
+}
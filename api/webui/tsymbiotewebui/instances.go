@@ -0,0 +1,64 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+// instanceAdapters replaces every adapter serving several tailscaled with one adapter per instance, named <adapter>/instance/<name>.
+// Instances aren't cached, they come and go with the pods on a node.
+// Adapters that don't answer, or only serve one tailscaled, are kept as they are.
+// The adapters that answered are returned alongside.
+func (t *TSymbioteUIServer) instanceAdapters(ctx context.Context, r *tsymbiote.HTTPRequest, adapters []string) ([]string, []string) {
+	expanded := make([][]string, len(adapters))
+	answered := make([]bool, len(adapters))
+	var wg sync.WaitGroup
+	for i, adapter := range adapters {
+		expanded[i] = []string{adapter}
+		wg.Go(func() {
+			outgoingctx, outgoingcancel := context.WithTimeout(ctx, consts.OutgoingRequestTimeout)
+			defer outgoingcancel()
+
+			resp, err := t.CallAdapter(outgoingctx, r, "POST", adapter, paths.Instances.Adapter(), nil)
+			if err != nil {
+				r.Log.Infow("failed to list adapter instances", "adapter", adapter, "error", err)
+				return
+			}
+			defer resp.Close()
+
+			var instances []types.HostInstance
+			err = json.NewDecoder(resp).Decode(&instances)
+			if err != nil {
+				r.Log.Infow("failed to decode adapter instances", "adapter", adapter, "error", err)
+				return
+			}
+			answered[i] = true
+			if len(instances) == 0 {
+				return
+			}
+
+			names := make([]string, 0, len(instances))
+			for _, instance := range instances {
+				names = append(names, adapter+consts.InstancePathPrefix+instance.Name)
+			}
+			expanded[i] = names
+		})
+	}
+	wg.Wait()
+
+	out := []string{}
+	listed := []string{}
+	for i, names := range expanded {
+		out = append(out, names...)
+		if answered[i] {
+			listed = append(listed, adapters[i])
+		}
+	}
+	return out, listed
+}
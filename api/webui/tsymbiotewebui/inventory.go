@@ -0,0 +1,256 @@
+package tsymbiotewebui
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+)
+
+const defaultExpiringDays = 30
+
+// fleetPrefs is the subset of ipn.Prefs fleet reports look at.
+type fleetPrefs struct {
+	AdvertiseRoutes []netip.Prefix
+	ExitNodeID      tailcfg.StableNodeID
+	RouteAll        bool
+}
+
+// InventoryHost is a single row of the fleet inventory.
+type InventoryHost struct {
+	Host    string   `json:"host"`
+	Adapter string   `json:"adapter"`
+	Tags    []string `json:"tags,omitempty"`
+	Version string   `json:"version,omitempty"`
+	// VersionDrift is set when the host isn't on the newest version in the fleet.
+	VersionDrift bool   `json:"versionDrift"`
+	OS           string `json:"os,omitempty"`
+	Online       bool   `json:"online"`
+	// KeyExpiry is empty when key expiry is disabled.
+	KeyExpiry      *time.Time `json:"keyExpiry,omitempty"`
+	KeyExpiring    bool       `json:"keyExpiring"`
+	KeyExpired     bool       `json:"keyExpired"`
+	Health         []string   `json:"health,omitempty"`
+	ExitNode       string     `json:"exitNode,omitempty"`
+	ExitNodeOnline bool       `json:"exitNodeOnline,omitempty"`
+	Capabilities   []string   `json:"capabilities,omitempty"`
+	// AdvertisedRoutes leaves out the exit node routes, AdvertisesExitNode is set instead.
+	AdvertisedRoutes   []string `json:"advertisedRoutes,omitempty"`
+	AdvertisesExitNode bool     `json:"advertisesExitNode"`
+	Error              string   `json:"error,omitempty"`
+}
+
+// InventoryReport is the fleet inventory with drift and expiry summaries.
+type InventoryReport struct {
+	Hosts         []InventoryHost `json:"hosts"`
+	LatestVersion string          `json:"latestVersion,omitempty"`
+	// Versions counts hosts per version.
+	Versions     map[string]int `json:"versions"`
+	ExpiringDays int            `json:"expiringDays"`
+	Drifted      int            `json:"drifted"`
+	Expiring     int            `json:"expiring"`
+	Unhealthy    int            `json:"unhealthy"`
+}
+
+// Inventory reports version, OS, key expiry, health, exit node and routes for every host.
+// ?expiringDays=N flags keys expiring within N days, ?selector= limits the hosts and ?format=csv downloads a spreadsheet.
+func (t *TSymbioteUIServer) Inventory(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	query := r.URL.Query()
+
+	expiringDays := defaultExpiringDays
+	if raw := query.Get("expiringDays"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 0 {
+			r.Log.Errorw("invalid expiringDays", "expiringDays", raw)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+		expiringDays = days
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		r.Log.Errorw("unknown inventory format", "format", format)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	fleet, err := t.collectFleet(r.Context(), r, query.Get("selector"), paths.Prefs)
+	if err != nil {
+		r.Log.Errorw("failed to collect fleet", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	report := buildInventory(fleet, time.Now(), time.Duration(expiringDays)*24*time.Hour)
+	report.ExpiringDays = expiringDays
+
+	if format != "csv" {
+		t.WriteJson(w, r, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="inventory.csv"`)
+	err = writeInventoryCSV(w, report)
+	if err != nil {
+		r.Log.Errorw("failed to write inventory csv", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+	}
+}
+
+func buildInventory(fleet []*fleetHost, now time.Time, expiringWithin time.Duration) *InventoryReport {
+	report := &InventoryReport{
+		Hosts:    []InventoryHost{},
+		Versions: map[string]int{},
+	}
+
+	for _, host := range fleet {
+		row := InventoryHost{
+			Host:    host.Host,
+			Adapter: host.Adapter,
+			Tags:    host.Tags,
+			Error:   host.Error,
+		}
+
+		if host.Status != nil {
+			fillInventoryStatus(&row, host.Status, now, expiringWithin)
+			report.Versions[row.Version]++
+			if versionLess(report.LatestVersion, row.Version) {
+				report.LatestVersion = row.Version
+			}
+		}
+
+		prefs := &fleetPrefs{}
+		if host.Decode(paths.Prefs, prefs) == nil {
+			for _, route := range prefs.AdvertiseRoutes {
+				if route.Bits() == 0 {
+					row.AdvertisesExitNode = true
+					continue
+				}
+				row.AdvertisedRoutes = append(row.AdvertisedRoutes, route.String())
+			}
+		}
+
+		report.Hosts = append(report.Hosts, row)
+	}
+
+	for i, row := range report.Hosts {
+		if row.Version != "" && row.Version != report.LatestVersion {
+			report.Hosts[i].VersionDrift = true
+			report.Drifted++
+		}
+		if row.KeyExpiring || row.KeyExpired {
+			report.Expiring++
+		}
+		if len(row.Health) > 0 {
+			report.Unhealthy++
+		}
+	}
+
+	return report
+}
+
+func fillInventoryStatus(row *InventoryHost, status *ipnstate.Status, now time.Time, expiringWithin time.Duration) {
+	// Version includes the build, IE: 1.92.4-t0123abcd-g4567ef, drift only cares about the release.
+	row.Version, _, _ = strings.Cut(status.Version, "-")
+	row.Health = status.Health
+
+	self := status.Self
+	row.OS = self.OS
+	row.Online = self.Online
+	row.KeyExpiry = self.KeyExpiry
+	if self.KeyExpiry != nil {
+		row.KeyExpired = self.Expired || !self.KeyExpiry.After(now)
+		row.KeyExpiring = !row.KeyExpired && self.KeyExpiry.Before(now.Add(expiringWithin))
+	}
+
+	for _, capability := range self.Capabilities {
+		row.Capabilities = append(row.Capabilities, string(capability))
+	}
+
+	if status.ExitNodeStatus != nil {
+		row.ExitNode = exitNodeName(status, status.ExitNodeStatus.ID)
+		row.ExitNodeOnline = status.ExitNodeStatus.Online
+	}
+}
+
+// exitNodeName finds the exit node's hostname in the peer list, falling back to its ID.
+func exitNodeName(status *ipnstate.Status, id tailcfg.StableNodeID) string {
+	for _, peer := range status.Peer {
+		if peer.ID == id {
+			return peer.HostName
+		}
+	}
+	return string(id)
+}
+
+// versionLess compares dotted release versions numerically, IE: 1.9.0 < 1.10.0.
+func versionLess(a string, b string) bool {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+	for i := range max(len(aParts), len(bParts)) {
+		var aNum, bNum int
+		if i < len(aParts) {
+			aNum, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bNum, _ = strconv.Atoi(bParts[i])
+		}
+		if aNum != bNum {
+			return aNum < bNum
+		}
+	}
+	return false
+}
+
+func writeInventoryCSV(w http.ResponseWriter, report *InventoryReport) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{
+		"host", "adapter", "tags", "version", "version_drift", "os", "online",
+		"key_expiry", "key_expiring", "key_expired", "health", "exit_node",
+		"advertised_routes", "advertises_exit_node", "error",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range report.Hosts {
+		expiry := ""
+		if row.KeyExpiry != nil {
+			expiry = row.KeyExpiry.UTC().Format(time.RFC3339)
+		}
+
+		err = out.Write([]string{
+			row.Host,
+			row.Adapter,
+			strings.Join(row.Tags, " "),
+			row.Version,
+			strconv.FormatBool(row.VersionDrift),
+			row.OS,
+			strconv.FormatBool(row.Online),
+			expiry,
+			strconv.FormatBool(row.KeyExpiring),
+			strconv.FormatBool(row.KeyExpired),
+			strings.Join(row.Health, "; "),
+			row.ExitNode,
+			strings.Join(slices.Sorted(slices.Values(row.AdvertisedRoutes)), " "),
+			strconv.FormatBool(row.AdvertisesExitNode),
+			row.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
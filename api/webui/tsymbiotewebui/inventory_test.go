@@ -0,0 +1,79 @@
+package tsymbiotewebui
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+	"tailscale.com/types/key"
+)
+
+var _ = Describe("buildInventory", func() {
+	now := time.Date(2025, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	It("Should flag version drift, expiring keys and unhealthy hosts", func() {
+		soon := now.Add(10 * 24 * time.Hour)
+		past := now.Add(-time.Hour)
+
+		fleet := []*fleetHost{
+			newTestFleetHost("a", &ipnstate.Status{
+				Version: "1.92.4-t0123abcd-g4567ef",
+				Health:  []string{"dns unreachable"},
+				Self:    &ipnstate.PeerStatus{OS: "linux", Online: true, KeyExpiry: &soon},
+			}, map[paths.KnownPath]any{
+				paths.Prefs: fleetPrefs{AdvertiseRoutes: []netip.Prefix{
+					netip.MustParsePrefix("10.0.0.0/24"),
+					netip.MustParsePrefix("0.0.0.0/0"),
+				}},
+			}),
+			newTestFleetHost("b", &ipnstate.Status{
+				Version:        "1.90.1",
+				Self:           &ipnstate.PeerStatus{OS: "windows", KeyExpiry: &past},
+				ExitNodeStatus: &ipnstate.ExitNodeStatus{ID: "exit-id", Online: true},
+				Peer: map[key.NodePublic]*ipnstate.PeerStatus{
+					key.NewNode().Public(): {ID: tailcfg.StableNodeID("exit-id"), HostName: "exit"},
+				},
+			}, nil),
+			newTestFleetHost("c", nil, nil),
+		}
+		fleet[2].Error = "adapter unreachable"
+
+		report := buildInventory(fleet, now, 30*24*time.Hour)
+
+		Expect(report.LatestVersion).To(Equal("1.92.4"))
+		Expect(report.Versions).To(Equal(map[string]int{"1.92.4": 1, "1.90.1": 1}))
+		Expect(report.Drifted).To(Equal(1))
+		Expect(report.Expiring).To(Equal(2))
+		Expect(report.Unhealthy).To(Equal(1))
+
+		Expect(report.Hosts).To(HaveLen(3))
+		a, b, c := report.Hosts[0], report.Hosts[1], report.Hosts[2]
+
+		Expect(a.VersionDrift).To(BeFalse())
+		Expect(a.KeyExpiring).To(BeTrue())
+		Expect(a.KeyExpired).To(BeFalse())
+		Expect(a.AdvertisedRoutes).To(Equal([]string{"10.0.0.0/24"}))
+		Expect(a.AdvertisesExitNode).To(BeTrue())
+
+		Expect(b.VersionDrift).To(BeTrue())
+		Expect(b.KeyExpired).To(BeTrue())
+		Expect(b.KeyExpiring).To(BeFalse())
+		Expect(b.ExitNode).To(Equal("exit"))
+		Expect(b.ExitNodeOnline).To(BeTrue())
+
+		Expect(c.Adapter).To(Equal("c-adapter"))
+		Expect(c.Error).To(Equal("adapter unreachable"))
+		Expect(c.Version).To(BeEmpty())
+		Expect(c.VersionDrift).To(BeFalse())
+	})
+
+	It("Should compare versions numerically", func() {
+		Expect(versionLess("1.9.0", "1.10.0")).To(BeTrue())
+		Expect(versionLess("1.10.0", "1.9.0")).To(BeFalse())
+		Expect(versionLess("", "1.0.0")).To(BeTrue())
+	})
+})
@@ -0,0 +1,499 @@
+package tsymbiotewebui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Job states
+const (
+	JobQueued   = "queued"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobCanceled = "canceled"
+	JobFailed   = "failed"
+)
+
+// maxRetainedJobs is how many jobs are kept in memory, the oldest finished jobs are dropped first.
+const maxRetainedJobs = 200
+
+// JobInput is the body used to submit a job, Input is the same body the synchronous endpoint takes.
+type JobInput struct {
+	Kind  string          `json:"kind"`
+	Input json.RawMessage `json:"input"`
+}
+
+// Job is a fan-out request running in the background, results are appended as hosts complete.
+type Job struct {
+	ID       string            `json:"id"`
+	Kind     string            `json:"kind"`
+	User     string            `json:"user,omitempty"`
+	TraceID  string            `json:"traceId"`
+	State    string            `json:"state"`
+	Error    string            `json:"error,omitempty"`
+	Hosts    []string          `json:"hosts"`
+	Total    int               `json:"total"`
+	Done     int               `json:"done"`
+	Created  time.Time         `json:"created"`
+	Started  time.Time         `json:"started,omitzero"`
+	Finished time.Time         `json:"finished,omitzero"`
+	Results  []json.RawMessage `json:"results,omitempty"`
+	Summary  *FanOutSummary    `json:"summary,omitempty"`
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// snapshot copies the job for serialization, results before since are skipped.
+func (j *Job) snapshot(since int, withResults bool) *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	out := &Job{
+		ID:       j.ID,
+		Kind:     j.Kind,
+		User:     j.User,
+		TraceID:  j.TraceID,
+		State:    j.State,
+		Error:    j.Error,
+		Hosts:    j.Hosts,
+		Total:    j.Total,
+		Done:     j.Done,
+		Created:  j.Created,
+		Started:  j.Started,
+		Finished: j.Finished,
+		Summary:  j.Summary,
+	}
+
+	if withResults && since < len(j.Results) {
+		out.Results = slices.Clone(j.Results[max(since, 0):])
+	}
+	return out
+}
+
+func (j *Job) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.State == JobDone || j.State == JobCanceled || j.State == JobFailed
+}
+
+// jobManager tracks jobs and limits how many job calls can run against a single adapter at once.
+type jobManager struct {
+	adapterConcurrency int
+
+	mu    sync.Mutex
+	jobs  []*Job
+	slots map[string]chan struct{}
+}
+
+func newJobManager(adapterConcurrency int) *jobManager {
+	return &jobManager{
+		adapterConcurrency: max(adapterConcurrency, 1),
+		slots:              map[string]chan struct{}{},
+	}
+}
+
+func (m *jobManager) add(job *Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.jobs = append(m.jobs, job)
+
+	// Drop the oldest finished jobs once we're over the limit.
+	for i := 0; len(m.jobs) > maxRetainedJobs && i < len(m.jobs); {
+		if m.jobs[i].finished() {
+			m.jobs = slices.Delete(m.jobs, i, i+1)
+			continue
+		}
+		i++
+	}
+}
+
+func (m *jobManager) get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.ID == id {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+func (m *jobManager) list(user string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := []*Job{}
+	for _, job := range slices.Backward(m.jobs) {
+		if job.User == user {
+			out = append(out, job.snapshot(0, false))
+		}
+	}
+	return out
+}
+
+func (m *jobManager) slot(host string) chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slot, ok := m.slots[host]
+	if !ok {
+		slot = make(chan struct{}, m.adapterConcurrency)
+		m.slots[host] = slot
+	}
+	return slot
+}
+
+// acquire blocks until host has a free slot, the returned func releases it.
+// Slots are taken per call so a slow host only holds up the calls made to it.
+func (m *jobManager) acquire(ctx context.Context, host string) (func(), error) {
+	slot := m.slot(host)
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type jobManagerKey struct{}
+
+// withJobManager marks the calls made under ctx as job calls, fanOutCaller takes a host slot from m for each of them.
+func withJobManager(ctx context.Context, m *jobManager) context.Context {
+	return context.WithValue(ctx, jobManagerKey{}, m)
+}
+
+func jobManagerFrom(ctx context.Context) (*jobManager, bool) {
+	m, ok := ctx.Value(jobManagerKey{}).(*jobManager)
+	return m, ok
+}
+
+// jobHandlers are the fan-out endpoints that can be run as jobs.
+func (t *TSymbioteUIServer) jobHandlers() map[paths.KnownPath]tsymbiote.HandlerFunc {
+	return map[paths.KnownPath]tsymbiote.HandlerFunc{
+		paths.Status:        t.RelativeJSON,
+		paths.Prefs:         t.RelativeJSON,
+		paths.DriveShares:   t.RelativeJSON,
+		paths.DNSConfig:     t.RelativeJSON,
+		paths.ServeConfig:   t.RelativeJSON,
+		paths.AppConnRoutes: t.RelativeJSON,
+		paths.DERPMap:       t.RelativeJSON,
+		paths.PacketFilter:  t.RelativeJSON,
+		paths.Ping:          t.Ping,
+		paths.QueryDNS:      t.QueryDNS,
+		paths.Pprof:         t.Pprof,
+		paths.Goroutines:    t.Goroutines,
+	}
+}
+
+// jobTargets pulls the hosts and expected result count out of any fan-out input.
+func jobTargets(input json.RawMessage) ([]string, int, error) {
+	targets := struct {
+		Hosts []string `json:"hosts"`
+		Args  []struct {
+			Host    string   `json:"host"`
+			Targets []string `json:"targets"`
+		} `json:"args"`
+	}{}
+
+	err := json.Unmarshal(input, &targets)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hosts := slices.Clone(targets.Hosts)
+	total := len(targets.Hosts)
+	// Ping takes source hosts with a list of targets each.
+	for _, arg := range targets.Args {
+		hosts = append(hosts, arg.Host)
+		total += len(arg.Targets)
+	}
+
+	slices.Sort(hosts)
+	return slices.Compact(hosts), total, nil
+}
+
+// resolveJobSelectors rewrites selectors in a job input into explicit hosts.
+// The input is otherwise left untouched since it is passed straight to the handler.
+func (t *TSymbioteUIServer) resolveJobSelectors(r *tsymbiote.HTTPRequest, input json.RawMessage) (json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(input, &fields)
+	if err != nil {
+		// Not an object, leave it to jobTargets to reject.
+		return input, nil
+	}
+
+	resolver := t.newHostResolver(r)
+
+	if _, ok := fields["selector"]; ok {
+		selection := struct {
+			Hosts    []string `json:"hosts"`
+			Selector string   `json:"selector"`
+		}{}
+		err = json.Unmarshal(input, &selection)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts, err := resolver.Resolve(r.Context(), selection.Hosts, selection.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		fields["hosts"], err = json.Marshal(hosts)
+		if err != nil {
+			return nil, err
+		}
+		delete(fields, "selector")
+	}
+
+	if rawArgs, ok := fields["args"]; ok {
+		args := []pingTargets{}
+		err = json.Unmarshal(rawArgs, &args)
+		if err != nil {
+			return nil, err
+		}
+
+		args, err = t.expandPingTargets(r, args)
+		if err != nil {
+			return nil, err
+		}
+
+		fields["args"], err = json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// SubmitJob starts a fan-out request in the background and returns the job immediately.
+func (t *TSymbioteUIServer) SubmitJob(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	input := &JobInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode job input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	var kind paths.KnownPath
+	var handler tsymbiote.HandlerFunc
+	for path, pathHandler := range t.jobHandlers() {
+		if path.String() == input.Kind {
+			kind, handler = path, pathHandler
+			break
+		}
+	}
+
+	if handler == nil {
+		r.Log.Errorw("unsupported job kind", "kind", input.Kind)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	// Selectors are resolved up front so the job knows which adapters it needs.
+	input.Input, err = t.resolveJobSelectors(r, input.Input)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	hosts, total, err := jobTargets(input.Input)
+	if err != nil {
+		r.Log.Errorw("failed to decode job targets", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	// Jobs outlive the request, they're only stopped by cancel or shutdown.
+	jobCtx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		ID:      uuid.New().String(),
+		Kind:    kind.String(),
+		User:    r.UserName,
+		TraceID: r.TraceID,
+		State:   JobQueued,
+		Hosts:   hosts,
+		Total:   total,
+		Created: time.Now(),
+		cancel:  cancel,
+	}
+
+	t.jobs.add(job)
+	r.Log.Infow("job submitted", "job", job.ID, "kind", job.Kind, "hosts", len(hosts))
+
+	// Use the manager so shutdown cancels and waits for running jobs.
+	t.RunWSFunc(t.runJob(jobCtx, job, kind, handler, input.Input))
+
+	t.WriteJson(w, r, job.snapshot(0, false))
+}
+
+func (t *TSymbioteUIServer) runJob(jobCtx context.Context, job *Job, kind paths.KnownPath, handler tsymbiote.HandlerFunc, input json.RawMessage) tsymbiote.WebsocketFunc {
+	return func(shutdownCtx context.Context) {
+		defer job.cancel()
+		stopShutdown := context.AfterFunc(shutdownCtx, job.cancel)
+		defer stopShutdown()
+
+		log := t.Log.With(zap.String("trace_id", job.TraceID), zap.String("job", job.ID))
+		if job.User != "" {
+			log = log.With(zap.String("user", job.User))
+		}
+
+		finish := func(state string, err error) {
+			job.mu.Lock()
+			defer job.mu.Unlock()
+			job.State = state
+			job.Finished = time.Now()
+			if err != nil {
+				job.Error = err.Error()
+			}
+			log.Infow("job finished", "state", state, "elapsed", job.Finished.Sub(job.Created))
+		}
+
+		job.mu.Lock()
+		job.State = JobRunning
+		job.Started = time.Now()
+		job.mu.Unlock()
+
+		// Run the synchronous handler in streaming mode, results are captured as each host finishes.
+		// Every adapter call the handler makes waits on a slot for its host, see fanOutCaller.call.
+		request, err := http.NewRequestWithContext(withJobManager(jobCtx, t.jobs), http.MethodPost, kind.WebUI()+"?"+url.Values{"stream": {fanOutNDJSON}}.Encode(), io.NopCloser(bytes.NewReader(input)))
+		if err != nil {
+			finish(JobFailed, err)
+			return
+		}
+
+		writer := &jobResponseWriter{job: job, header: http.Header{}}
+		handler(writer, &tsymbiote.HTTPRequest{
+			Request:  request,
+			Log:      log,
+			TraceID:  job.TraceID,
+			UserName: job.User,
+		})
+
+		switch {
+		case jobCtx.Err() != nil:
+			finish(JobCanceled, jobCtx.Err())
+		case writer.status >= http.StatusBadRequest:
+			finish(JobFailed, fmt.Errorf("job handler returned %d %s", writer.status, http.StatusText(writer.status)))
+		default:
+			finish(JobDone, writer.err)
+		}
+	}
+}
+
+// jobResponseWriter collects NDJSON fan-out records written by a handler into a job.
+type jobResponseWriter struct {
+	job    *Job
+	header http.Header
+	status int
+	buffer []byte
+	err    error
+}
+
+func (w *jobResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *jobResponseWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
+
+func (w *jobResponseWriter) Write(data []byte) (int, error) {
+	w.buffer = append(w.buffer, data...)
+
+	for {
+		line, rest, found := bytes.Cut(w.buffer, []byte("\n"))
+		if !found {
+			break
+		}
+		w.buffer = rest
+
+		record := struct {
+			Type    string          `json:"type"`
+			Result  json.RawMessage `json:"result"`
+			Summary *FanOutSummary  `json:"summary"`
+		}{}
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			w.err = errors.Join(w.err, err)
+			continue
+		}
+
+		w.job.mu.Lock()
+		switch record.Type {
+		case FanOutRecordResult:
+			w.job.Results = append(w.job.Results, record.Result)
+			w.job.Done++
+		case FanOutRecordSummary:
+			w.job.Summary = record.Summary
+		}
+		w.job.mu.Unlock()
+	}
+
+	return len(data), nil
+}
+
+// Flush satisfies http.ResponseController, records are already handled as they are written.
+func (w *jobResponseWriter) Flush() {}
+
+// Jobs lists recent jobs for the requesting user, newest first.
+func (t *TSymbioteUIServer) Jobs(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	t.WriteJson(w, r, t.jobs.list(r.UserName))
+}
+
+// GetJob returns job progress and results, ?since=N only returns results after the first N.
+func (t *TSymbioteUIServer) GetJob(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	job, ok := t.jobs.get(r.PathValue("id"))
+	if !ok || job.User != r.UserName {
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	since := 0
+	if rawSince := r.URL.Query().Get("since"); rawSince != "" {
+		parsed, err := strconv.Atoi(rawSince)
+		if err != nil {
+			r.Log.Errorw("invalid since param", "since", rawSince)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	t.WriteJson(w, r, job.snapshot(since, true))
+}
+
+// CancelJob stops a queued or running job, results collected so far are kept.
+func (t *TSymbioteUIServer) CancelJob(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	job, ok := t.jobs.get(r.PathValue("id"))
+	if !ok || job.User != r.UserName {
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	job.cancel()
+
+	t.WriteJson(w, r, job.snapshot(0, false))
+}
@@ -0,0 +1,53 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Job calls", func() {
+	It("Should only hold up calls to the host that is busy", func() {
+		jobs := newJobManager(1)
+		ctx := withJobManager(context.Background(), jobs)
+		caller := &fanOutCaller{timeout: time.Second, slots: make(chan struct{}, 4)}
+
+		call := func(release <-chan struct{}) func(context.Context) (io.ReadCloser, error) {
+			return func(context.Context) (io.ReadCloser, error) {
+				<-release
+				return io.NopCloser(strings.NewReader("")), nil
+			}
+		}
+		handle := func(io.Reader) error { return nil }
+
+		slow := make(chan struct{})
+		slowDone := make(chan struct{})
+		go func() {
+			defer close(slowDone)
+			caller.call(ctx, call(slow), "host-a", 0, handle)
+		}()
+
+		open := make(chan struct{})
+		close(open)
+
+		// Give the slow call time to take host-a's only slot.
+		Eventually(func() int { return len(jobs.slot("host-a")) }).Should(Equal(1))
+
+		_, err := caller.call(ctx, call(open), "host-b", 0, handle)
+		Expect(err).NotTo(HaveOccurred())
+
+		waitCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+		defer cancel()
+		_, err = caller.call(waitCtx, call(open), "host-a", 0, handle)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		close(slow)
+		Eventually(slowDone).Should(BeClosed())
+		_, err = caller.call(ctx, call(open), "host-a", 0, handle)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,139 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+// defaultPortmapDuration matches the local client's default when no duration is given.
+const defaultPortmapDuration = time.Second * 5
+
+// NATComparison lines up every host's NAT report and groups hosts by what stands between them and a direct path.
+type NATComparison struct {
+	Hosts []types.NATReport `json:"hosts"`
+	// PortMapped hosts got an external mapping from their router.
+	PortMapped []string `json:"portMapped"`
+	// NoPortMapping hosts have no PCP, PMP or UPnP service or got no mapping from it.
+	NoPortMapping []string `json:"noPortMapping"`
+	// VaryingPorts hosts are behind a NAT that maps per destination, peers will often need DERP to reach them.
+	VaryingPorts []string `json:"varyingPorts"`
+	// NoPublicEndpoint hosts only advertise private endpoints.
+	NoPublicEndpoint []string `json:"noPublicEndpoint"`
+	// SharedPublicIPs are public IPs more than one host is seen behind, IE: the same office NAT.
+	SharedPublicIPs map[string][]string `json:"sharedPublicIps"`
+}
+
+// NAT runs the port mapping probe on every host and compares the results along with each host's endpoints.
+func (t *TSymbioteUIServer) NAT(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+	input := &types.NATInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode nat input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	duration := defaultPortmapDuration
+	if input.Duration != "" {
+		duration, err = time.ParseDuration(input.Duration)
+		if err != nil || duration <= 0 || duration > consts.MaxPortmapDuration {
+			r.Log.Errorw("invalid portmap duration", "duration", input.Duration, "error", err)
+			r.SetStatusCode(w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, duration)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(&types.NATInput{Type: input.Type, Duration: input.Duration})
+	if err != nil {
+		r.Log.Errorw("failed to marshal nat input", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	reports := make([]types.NATReport, len(input.Hosts))
+	var wg sync.WaitGroup
+	for i, targetHost := range input.Hosts {
+		wg.Go(func() {
+			report := types.NATReport{}
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.NAT.Adapter(), body, func(resp io.Reader) error {
+				return json.NewDecoder(resp).Decode(&report)
+			})
+			report.Host = targetHost
+			report.Attempts = attempts
+			if err != nil {
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
+				report.Error = err.Error()
+			}
+			reports[i] = report
+		})
+	}
+	wg.Wait()
+
+	t.WriteJson(w, r, compareNAT(reports))
+}
+
+func compareNAT(reports []types.NATReport) *NATComparison {
+	comparison := &NATComparison{
+		Hosts:            reports,
+		PortMapped:       []string{},
+		NoPortMapping:    []string{},
+		VaryingPorts:     []string{},
+		NoPublicEndpoint: []string{},
+		SharedPublicIPs:  map[string][]string{},
+	}
+
+	behind := map[string][]string{}
+	for _, report := range reports {
+		if report.Error != "" {
+			continue
+		}
+
+		if report.Portmap.Mapping != "" {
+			comparison.PortMapped = append(comparison.PortMapped, report.Host)
+		} else {
+			comparison.NoPortMapping = append(comparison.NoPortMapping, report.Host)
+		}
+		if report.VaryingPorts {
+			comparison.VaryingPorts = append(comparison.VaryingPorts, report.Host)
+		}
+		if len(report.PublicIPs) == 0 {
+			comparison.NoPublicEndpoint = append(comparison.NoPublicEndpoint, report.Host)
+		}
+		for _, ip := range report.PublicIPs {
+			if !slices.Contains(behind[ip], report.Host) {
+				behind[ip] = append(behind[ip], report.Host)
+			}
+		}
+	}
+
+	for ip, hosts := range behind {
+		if len(hosts) > 1 {
+			comparison.SharedPublicIPs[ip] = hosts
+		}
+	}
+	return comparison
+}
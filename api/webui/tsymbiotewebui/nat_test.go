@@ -0,0 +1,25 @@
+package tsymbiotewebui
+
+import (
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("compareNAT", func() {
+	It("Should group hosts by port mapping, public endpoints and shared public IPs", func() {
+		comparison := compareNAT([]types.NATReport{
+			{Host: "a", Portmap: types.PortmapResult{Mapping: "203.0.113.5:41641"}, PublicIPs: []string{"203.0.113.5"}, VaryingPorts: true},
+			{Host: "b", PublicIPs: []string{"203.0.113.5", "198.51.100.7"}},
+			{Host: "c", PublicIPs: []string{}},
+			{Host: "d", Error: "adapter unreachable"},
+		})
+
+		Expect(comparison.Hosts).To(HaveLen(4))
+		Expect(comparison.PortMapped).To(Equal([]string{"a"}))
+		Expect(comparison.NoPortMapping).To(Equal([]string{"b", "c"}))
+		Expect(comparison.VaryingPorts).To(Equal([]string{"a"}))
+		Expect(comparison.NoPublicEndpoint).To(Equal([]string{"c"}))
+		Expect(comparison.SharedPublicIPs).To(Equal(map[string][]string{"203.0.113.5": {"a", "b"}}))
+	})
+})
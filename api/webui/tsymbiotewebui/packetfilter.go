@@ -0,0 +1,87 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/netip"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/tailcfg"
+)
+
+// CheckPacketFilter answers "would Destination accept this packet from Source" with the filter Destination enforces.
+// Source is looked up in Destination's netmap, by name or address, a source that isn't a peer there can't reach it at all.
+func (t *TSymbioteUIServer) CheckPacketFilter(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+	input := &types.FilterCheckInput{}
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		r.Log.Errorw("failed to decode filter check input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	err = input.Validate()
+	if err != nil {
+		r.Log.Errorw("invalid filter check input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	report := &types.PacketFilterReport{}
+	attempts, err := caller.CallHost(r.Context(), r, "POST", input.Destination, paths.PacketFilter.Adapter(), nil, func(resp io.Reader) error {
+		return json.NewDecoder(resp).Decode(report)
+	})
+	if err != nil {
+		r.Log.Errorw("failed to call adapter", "host", input.Destination, "attempts", len(attempts), "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+	report.Host = input.Destination
+
+	peer, ok := report.Peer(input.Source)
+	if !ok {
+		t.WriteJson(w, r, &types.FilterCheck{
+			Source:      input.Source,
+			Destination: input.Destination,
+			Proto:       input.Proto,
+			Port:        input.Port,
+			Reason:      "source is not a peer in the destination's netmap",
+			Matched:     []tailcfg.FilterRule{},
+			Attempts:    attempts,
+		})
+		return
+	}
+
+	var sources []netip.Addr
+	if addr, err := netip.ParseAddr(input.Source); err == nil {
+		// Only check the address family that was asked about.
+		sources = append(sources, addr)
+	} else {
+		for _, prefix := range peer.Addresses {
+			sources = append(sources, prefix.Addr())
+		}
+	}
+
+	check, err := report.Check(sources, input.Proto, input.Port)
+	if err != nil {
+		r.Log.Errorw("failed to check packet filter", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+	check.Source = input.Source
+	check.Attempts = attempts
+
+	t.WriteJson(w, r, check)
+}
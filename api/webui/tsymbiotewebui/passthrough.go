@@ -1,25 +1,30 @@
 package tsymbiotewebui
 
 import (
-	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
 )
 
 type defaultInput struct {
+	types.RequestOptions
 	Hosts []string `json:"hosts"`
+	// Selector picks hosts by expression in addition to Hosts, IE: tag:prod os:linux
+	Selector string `json:"selector,omitempty"`
 }
 
 type defaultResult struct {
 	Host   string         `json:"host,omitempty"`
 	Error  string         `json:"error,omitempty"`
 	Result map[string]any `json:"result,omitempty"`
+	// Attempts made against the adapter, more than one when retried.
+	Attempts []types.Attempt `json:"attempts,omitempty"`
 }
 
 // In most cases we're just passing a result back from the adapter to the Web UI.
@@ -47,6 +52,20 @@ func (t *TSymbioteUIServer) RelativeJSON(w http.ResponseWriter, r *tsymbiote.HTT
 		return
 	}
 
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
 	var channels []chan defaultResult
 	for _, targetHost := range input.Hosts {
 
@@ -56,39 +75,24 @@ func (t *TSymbioteUIServer) RelativeJSON(w http.ResponseWriter, r *tsymbiote.HTT
 			result := defaultResult{}
 			result.Host = targetHost
 
-			outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(consts.OutgoingRequestTimeout))
-			defer outgoingcancel()
-
-			resp, err := t.CallHost(outgoingctx, r, "POST", targetHost, targetPath, nil)
+			var callResult map[string]any
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, targetPath, nil, func(resp io.Reader) error {
+				callResult = map[string]any{}
+				return json.NewDecoder(resp).Decode(&callResult)
+			})
+			result.Attempts = attempts
 			if err != nil {
-				r.Log.Errorw("failed to call adapter", "error", err)
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
 				result.Error = err.Error()
 				ch <- result
 				return
 			}
 
-			defer resp.Close()
-
-			callResult := map[string]any{}
-			err = json.NewDecoder(resp).Decode(&callResult)
-			if err != nil {
-				r.Log.Errorw("failed to decode response from adapter", "error", err)
-				result.Error = err.Error()
-				ch <- result
-			}
-
 			result.Result = callResult
 			ch <- result
 		}()
 	}
 
-	results := []defaultResult{}
-	for _, channel := range channels {
-		res := <-channel
-		close(channel)
-		results = append(results, res)
-	}
-
 	// Unstructured is only needed on the adapter side in most cases.
-	t.WriteJson(w, r, results)
+	writeFanOut(t, w, r, channels, func(res defaultResult) string { return res.Error })
 }
@@ -1,22 +1,27 @@
 package tsymbiotewebui
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"maps"
 	"net/http"
 	"slices"
-	"time"
 
-	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
 )
 
 type Edge struct {
 	ID     string `json:"id"`
 	Source string `json:"source"`
 	Target string `json:"target"`
+	// Online and Active are the target's state as seen by the source.
+	Online bool `json:"online"`
+	Active bool `json:"active"`
+	// Connection is how the source reaches the target: direct, peer-relay, derp or idle.
+	Connection string `json:"connection"`
+	// Asymmetric is set when the target is an adapter host that doesn't see the source.
+	Asymmetric bool `json:"asymmetric,omitempty"`
 }
 
 type Node struct {
@@ -29,75 +34,107 @@ type NodeGraph struct {
 	Hosts []string `json:"hosts"`
 	Nodes []Node   `json:"nodes"`
 	Edges []Edge   `json:"edges"`
+	// Attempts made against each adapter, keyed by adapter hostname.
+	Attempts map[string][]types.Attempt `json:"attempts,omitempty"`
+	Analysis *PeerMapAnalysis           `json:"analysis,omitempty"`
 }
 
 type peerMapResult struct {
-	Nodes map[string]Node
-	Edges []Edge
-	Error string `json:"error,omitempty"`
+	Adapter  string
+	Host     string
+	Nodes    map[string]Node
+	Edges    []Edge
+	Error    string `json:"error,omitempty"`
+	Attempts []types.Attempt
 }
 
 func (t *TSymbioteUIServer) PeerMap(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
 
-	devices, err := t.GetDevicesWithTag("tag:tsymbiote-adapter")
+	// Options come from query params, IE: ?timeout=5s&retries=1 for far away regions.
+	opts, err := requestOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	caller, err := t.newFanOutCaller(opts, consts.PeerMapRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	nodeGraph, err := t.buildPeerMap(r, caller)
 	if err != nil {
 		r.Log.Errorw("failed to list devices", "error", err)
 		r.SetStatusCode(w, http.StatusInternalServerError)
 		return
 	}
 
-	outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(time.Second*1))
-	defer outgoingcancel()
+	t.WriteJson(w, r, nodeGraph)
+}
+
+// buildPeerMap calls Status on every adapter and merges each peer list into one graph.
+func (t *TSymbioteUIServer) buildPeerMap(r *tsymbiote.HTTPRequest, caller *fanOutCaller) (*NodeGraph, error) {
+	adapters, _, err := t.listAdapters(r.Context(), r)
+	if err != nil {
+		return nil, err
+	}
 
 	var channels []chan peerMapResult
-	for _, knownAdapter := range devices {
+	for _, knownAdapter := range adapters {
 		ch := make(chan peerMapResult)
 		channels = append(channels, ch)
 		go func() {
 			result := peerMapResult{
-				Nodes: map[string]Node{},
+				Adapter: knownAdapter,
+				Nodes:   map[string]Node{},
 			}
 
-			resp, err := t.CallAdapter(outgoingctx, r, "POST", knownAdapter.Hostname, paths.Status.Adapter(), nil)
+			status := map[string]any{}
+			hostname, attempts, err := t.callStatus(r.Context(), r, caller, knownAdapter, &status)
+			result.Attempts = attempts
 			if err != nil {
-				r.Log.Errorw("failed to call adapter", "adapter", knownAdapter.Hostname)
-				// Attempt to delete this adapter
-				t.DeleteAdapter(knownAdapter.Hostname)
+				// Adapters that left are dropped by resolveKnownHosts, a failed call alone isn't enough.
+				r.Log.Errorw("failed to call adapter", "adapter", knownAdapter, "attempts", len(attempts), "error", err)
 				result.Error = err.Error()
 				ch <- result
 				return
 			}
 
-			defer resp.Close()
-
-			status := map[string]any{}
-			err = json.NewDecoder(resp).Decode(&status)
-			if err != nil {
-				r.Log.Errorw("failed to decode peermap response from adapter", "error", err)
-				result.Error = err.Error()
+			result.Host = hostname
+			// Need some type assertions due to wanting to generally do passthrough.
+			// A node without peers has no Peer at all, anything else unexpected fails this adapter only.
+			peers, ok := status["Peer"].(map[string]any)
+			if !ok && status["Peer"] != nil {
+				result.Error = "unexpected Peer in status"
 				ch <- result
 				return
 			}
 
-			// Need some type assertions due to wanting to generally do passthrough.
-			self := status["Self"].(map[string]any)
-			hostname := self["HostName"].(string)
-			// dumb cache of known hosts value with the real value
-			t.SetKnownHost(hostname, knownAdapter.Hostname)
-			peers := status["Peer"].(map[string]any)
-
 			for _, peer := range peers {
-				peerstatus := peer.(map[string]any)
-				peerhostname := peerstatus["HostName"].(string)
+				peerstatus, ok := peer.(map[string]any)
+				if !ok {
+					continue
+				}
+				peerhostname, ok := peerstatus["HostName"].(string)
+				if !ok || peerhostname == "" {
+					continue
+				}
+				online, _ := peerstatus["Online"].(bool)
+				active, _ := peerstatus["Active"].(bool)
 				// Populate edges from self -> peer
 				result.Edges = append(result.Edges, Edge{
-					ID:     fmt.Sprintf("%s->%s", hostname, peerhostname),
-					Source: hostname,
-					Target: peerhostname,
+					ID:         fmt.Sprintf("%s->%s", hostname, peerhostname),
+					Source:     hostname,
+					Target:     peerhostname,
+					Online:     online,
+					Active:     active,
+					Connection: connectionType(peerstatus),
 				})
 
-				_, ok := result.Nodes[peerhostname]
-				if !ok {
+				if _, ok := result.Nodes[peerhostname]; !ok {
 					result.Nodes[peerhostname] = Node{
 						ID:    peerhostname,
 						Label: peerhostname,
@@ -111,9 +148,15 @@ func (t *TSymbioteUIServer) PeerMap(w http.ResponseWriter, r *tsymbiote.HTTPRequ
 
 	edges := []Edge{}
 	mergedNodeMap := map[string]Node{}
+	attempts := map[string][]types.Attempt{}
+	var observers []string
 	for _, channel := range channels {
 		res := <-channel
 		close(channel)
+		attempts[res.Adapter] = res.Attempts
+		if res.Host != "" {
+			observers = append(observers, res.Host)
+		}
 		edges = append(edges, res.Edges...)
 		// Copy into the merged map overwriting any duplicates.
 		maps.Copy(mergedNodeMap, res.Nodes)
@@ -121,11 +164,25 @@ func (t *TSymbioteUIServer) PeerMap(w http.ResponseWriter, r *tsymbiote.HTTPRequ
 
 	nodeSlice := slices.Collect(maps.Values(mergedNodeMap))
 
-	nodeGraph := NodeGraph{
-		Hosts: t.GetHosts(),
-		Nodes: nodeSlice,
-		Edges: edges,
-	}
+	return &NodeGraph{
+		Hosts:    t.GetHosts(),
+		Nodes:    nodeSlice,
+		Edges:    edges,
+		Attempts: attempts,
+		Analysis: analyzePeerMap(observers, edges),
+	}, nil
+}
 
-	t.WriteJson(w, r, nodeGraph)
+// connectionType reads the path to a peer from its PeerStatus, idle peers have no path yet.
+func connectionType(peer map[string]any) string {
+	if active, _ := peer["Active"].(bool); !active {
+		return "idle"
+	}
+	if addr, _ := peer["CurAddr"].(string); addr != "" {
+		return "direct"
+	}
+	if relay, _ := peer["PeerRelay"].(string); relay != "" {
+		return "peer-relay"
+	}
+	return "derp"
 }
@@ -0,0 +1,115 @@
+package tsymbiotewebui
+
+import (
+	"slices"
+	"strings"
+)
+
+// PeerStateConflict is a peer some adapters see as offline while others have an active connection to it.
+type PeerStateConflict struct {
+	Peer        string   `json:"peer"`
+	OfflineFrom []string `json:"offlineFrom"`
+	ActiveFrom  []string `json:"activeFrom"`
+}
+
+// PeerMapAnalysis compares what each adapter sees, only adapter hosts can be checked for symmetry.
+type PeerMapAnalysis struct {
+	// Asymmetric are A->B edges between adapter hosts with no B->A edge.
+	Asymmetric []Edge              `json:"asymmetric"`
+	Conflicts  []PeerStateConflict `json:"conflicts"`
+	// Groups are the connected components of adapter hosts over online edges, more than one is a partition.
+	Groups      [][]string `json:"groups"`
+	Partitioned bool       `json:"partitioned"`
+}
+
+// analyzePeerMap flags asymmetric edges in place and groups the observing adapter hosts into partitions.
+func analyzePeerMap(observers []string, edges []Edge) *PeerMapAnalysis {
+	analysis := &PeerMapAnalysis{
+		Asymmetric: []Edge{},
+		Conflicts:  []PeerStateConflict{},
+		Groups:     [][]string{},
+	}
+
+	isObserver := map[string]bool{}
+	for _, observer := range observers {
+		isObserver[observer] = true
+	}
+
+	seen := map[[2]string]bool{}
+	for _, edge := range edges {
+		seen[[2]string{edge.Source, edge.Target}] = true
+	}
+
+	conflicts := map[string]*PeerStateConflict{}
+	// Union find over adapter hosts, joined by any online edge in either direction.
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(host string) string {
+		if parent[host] == host {
+			return host
+		}
+		parent[host] = find(parent[host])
+		return parent[host]
+	}
+	for _, observer := range observers {
+		parent[observer] = observer
+	}
+
+	for i, edge := range edges {
+		if isObserver[edge.Target] && !seen[[2]string{edge.Target, edge.Source}] {
+			edges[i].Asymmetric = true
+			analysis.Asymmetric = append(analysis.Asymmetric, edges[i])
+		}
+
+		if isObserver[edge.Source] && isObserver[edge.Target] && edge.Online {
+			parent[find(edge.Source)] = find(edge.Target)
+		}
+
+		conflict, ok := conflicts[edge.Target]
+		if !ok {
+			conflict = &PeerStateConflict{Peer: edge.Target, OfflineFrom: []string{}, ActiveFrom: []string{}}
+			conflicts[edge.Target] = conflict
+		}
+		if !edge.Online {
+			conflict.OfflineFrom = append(conflict.OfflineFrom, edge.Source)
+		}
+		if edge.Active {
+			conflict.ActiveFrom = append(conflict.ActiveFrom, edge.Source)
+		}
+	}
+
+	for _, conflict := range conflicts {
+		if len(conflict.OfflineFrom) == 0 || len(conflict.ActiveFrom) == 0 {
+			continue
+		}
+		slices.Sort(conflict.OfflineFrom)
+		slices.Sort(conflict.ActiveFrom)
+		analysis.Conflicts = append(analysis.Conflicts, *conflict)
+	}
+	slices.SortFunc(analysis.Conflicts, func(a, b PeerStateConflict) int {
+		return strings.Compare(a.Peer, b.Peer)
+	})
+	slices.SortFunc(analysis.Asymmetric, func(a, b Edge) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	groups := map[string][]string{}
+	for _, observer := range observers {
+		root := find(observer)
+		groups[root] = append(groups[root], observer)
+	}
+	for _, group := range groups {
+		slices.Sort(group)
+		analysis.Groups = append(analysis.Groups, group)
+	}
+	// Biggest group first, it's usually the healthy side of a partition.
+	slices.SortFunc(analysis.Groups, func(a, b []string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a[0], b[0])
+	})
+	analysis.Partitioned = len(analysis.Groups) > 1
+
+	return analysis
+}
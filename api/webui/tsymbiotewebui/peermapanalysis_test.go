@@ -0,0 +1,49 @@
+package tsymbiotewebui
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("analyzePeerMap", func() {
+	edge := func(source string, target string, online bool, active bool) Edge {
+		return Edge{ID: source + "-" + target, Source: source, Target: target, Online: online, Active: active}
+	}
+
+	It("Should flag asymmetric edges, state conflicts and partitions", func() {
+		edges := []Edge{
+			edge("a", "b", true, true),
+			edge("b", "a", true, false),
+			edge("a", "c", true, false),
+			edge("c", "x", false, false),
+			edge("d", "x", true, true),
+		}
+
+		analysis := analyzePeerMap([]string{"a", "b", "c", "d"}, edges)
+
+		Expect(analysis.Asymmetric).To(HaveLen(1))
+		Expect(analysis.Asymmetric[0].ID).To(Equal("a-c"))
+		Expect(edges[2].Asymmetric).To(BeTrue())
+		// Edges to hosts without an adapter can't be checked.
+		Expect(edges[3].Asymmetric).To(BeFalse())
+
+		Expect(analysis.Conflicts).To(Equal([]PeerStateConflict{
+			{Peer: "x", OfflineFrom: []string{"c"}, ActiveFrom: []string{"d"}},
+		}))
+
+		Expect(analysis.Groups).To(Equal([][]string{{"a", "b", "c"}, {"d"}}))
+		Expect(analysis.Partitioned).To(BeTrue())
+	})
+
+	It("Should not join groups over offline edges", func() {
+		analysis := analyzePeerMap([]string{"a", "b"}, []Edge{
+			edge("a", "b", false, false),
+			edge("b", "a", false, false),
+		})
+
+		Expect(analysis.Asymmetric).To(BeEmpty())
+		Expect(analysis.Conflicts).To(BeEmpty())
+		Expect(analysis.Groups).To(Equal([][]string{{"a"}, {"b"}}))
+		Expect(analysis.Partitioned).To(BeTrue())
+	})
+})
@@ -0,0 +1,439 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+)
+
+const (
+	peerMapSnapshotsPath  = "/tmp/TSymbiote/peermap/"
+	peerMapSnapshotFormat = "20060102T150405Z"
+	peerMapSnapshotUser   = "tsymbiote-peermap-snapshots"
+	// defaultChangesWindow is how far back the change feed looks without ?since=.
+	defaultChangesWindow = time.Hour * 24
+)
+
+// snapshotNodeFields are the PeerStatus fields kept in snapshots, the full status is too big to store every interval.
+var snapshotNodeFields = []string{"DNSName", "OS", "TailscaleIPs", "Online", "Active", "ExitNode", "CurAddr", "Relay", "PeerRelay", "LastSeen"}
+
+// PeerMapSnapshot is the peer map as it was at Time.
+// Observers are the adapter hosts that answered, edges from anyone else are unknown rather than gone.
+type PeerMapSnapshot struct {
+	Time      time.Time `json:"time"`
+	Observers []string  `json:"observers"`
+	NodeGraph
+}
+
+// PeerMapChange is a node or edge that appeared, disappeared or changed connection type between two snapshots.
+type PeerMapChange struct {
+	Time     time.Time `json:"time"`
+	Previous time.Time `json:"previous"`
+	Kind     string    `json:"kind"`
+	ID       string    `json:"id"`
+	Source   string    `json:"source,omitempty"`
+	Target   string    `json:"target,omitempty"`
+	Change   string    `json:"change"`
+	From     string    `json:"from,omitempty"`
+	To       string    `json:"to,omitempty"`
+}
+
+// peerMapHistory snapshots the peer map on an interval and keeps them on disk for the retention period.
+type peerMapHistory struct {
+	*TSymbioteUIServer
+	interval  time.Duration
+	retention time.Duration
+
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (t *TSymbioteUIServer) newPeerMapHistory(interval time.Duration, retention time.Duration) (*peerMapHistory, error) {
+	h := &peerMapHistory{
+		TSymbioteUIServer: t,
+		interval:          interval,
+		retention:         retention,
+	}
+
+	err := os.MkdirAll(peerMapSnapshotsPath, 0770)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick up snapshots from before a restart.
+	entries, err := os.ReadDir(peerMapSnapshotsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		snapshotTime, err := time.Parse(peerMapSnapshotFormat, name)
+		if err != nil {
+			continue
+		}
+		h.times = append(h.times, snapshotTime)
+	}
+	slices.SortFunc(h.times, time.Time.Compare)
+
+	// Snapshots may have outlived the retention while we were down, or the retention may have been lowered.
+	expired := h.prune(time.Now())
+	if expired > 0 {
+		h.Log.Infow("removed expired peer map snapshots", "expired", expired)
+	}
+
+	return h, nil
+}
+
+// prune forgets snapshots older than the retention and removes their files, returning how many expired.
+func (h *peerMapHistory) prune(now time.Time) int {
+	h.mu.Lock()
+	expired := 0
+	for expired < len(h.times) && now.Sub(h.times[expired]) > h.retention {
+		expired++
+	}
+	removed := slices.Clone(h.times[:expired])
+	h.times = h.times[expired:]
+	h.mu.Unlock()
+
+	for _, old := range removed {
+		err := os.Remove(peerMapSnapshotFile(old))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			h.Log.Errorw("failed to remove expired peer map snapshot", "time", old, "error", err)
+		}
+	}
+	return len(removed)
+}
+
+func peerMapSnapshotFile(snapshotTime time.Time) string {
+	return filepath.Join(peerMapSnapshotsPath, fmt.Sprintf("%s.json", snapshotTime.UTC().Format(peerMapSnapshotFormat)))
+}
+
+// run takes a snapshot every interval until shutdown.
+func (h *peerMapHistory) run(shutdownCtx context.Context) {
+	h.Log.Infow("starting peer map snapshots", "interval", h.interval, "retention", h.retention)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		h.snapshot(shutdownCtx)
+
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *peerMapHistory) snapshot(ctx context.Context) {
+	r, err := h.backgroundRequest(ctx, peerMapSnapshotUser)
+	if err != nil {
+		h.Log.Errorw("failed to build snapshot request", "error", err)
+		return
+	}
+
+	caller, err := h.newFanOutCaller(types.RequestOptions{}, consts.PeerMapRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("failed to create snapshot caller", "error", err)
+		return
+	}
+
+	graph, err := h.buildPeerMap(r, caller)
+	if err != nil || ctx.Err() != nil {
+		r.Log.Errorw("failed to build peer map snapshot", "error", err)
+		return
+	}
+
+	snapshot := &PeerMapSnapshot{
+		// Second precision, it's what the file names hold.
+		Time:      time.Now().UTC().Truncate(time.Second),
+		Observers: []string{},
+		NodeGraph: *graph,
+	}
+	snapshot.Attempts = nil
+	for _, group := range graph.Analysis.Groups {
+		snapshot.Observers = append(snapshot.Observers, group...)
+	}
+	slices.Sort(snapshot.Observers)
+
+	for i, node := range snapshot.Nodes {
+		peer, ok := node.Data.(map[string]any)
+		if !ok {
+			continue
+		}
+		trimmed := map[string]any{}
+		for _, field := range snapshotNodeFields {
+			if value, ok := peer[field]; ok {
+				trimmed[field] = value
+			}
+		}
+		snapshot.Nodes[i].Data = trimmed
+	}
+	slices.SortFunc(snapshot.Nodes, func(a, b Node) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		r.Log.Errorw("failed to marshal peer map snapshot", "error", err)
+		return
+	}
+
+	err = os.WriteFile(peerMapSnapshotFile(snapshot.Time), data, 0660)
+	if err != nil {
+		r.Log.Errorw("failed to write peer map snapshot", "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.times = append(h.times, snapshot.Time)
+	h.mu.Unlock()
+
+	expired := h.prune(snapshot.Time)
+	r.Log.Infow("took peer map snapshot", "nodes", len(snapshot.Nodes), "edges", len(snapshot.Edges), "expired", expired)
+}
+
+func loadPeerMapSnapshot(snapshotTime time.Time) (*PeerMapSnapshot, error) {
+	data, err := os.ReadFile(peerMapSnapshotFile(snapshotTime))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &PeerMapSnapshot{}
+	err = json.Unmarshal(data, snapshot)
+	return snapshot, err
+}
+
+// between returns the snapshot times in [since, until] plus the one before since to diff against.
+func (h *peerMapHistory) between(since time.Time, until time.Time) []time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	start, _ := slices.BinarySearchFunc(h.times, since, time.Time.Compare)
+	start = max(start-1, 0)
+	end, found := slices.BinarySearchFunc(h.times, until, time.Time.Compare)
+	if found {
+		end++
+	}
+	if start >= end {
+		return nil
+	}
+	return slices.Clone(h.times[start:end])
+}
+
+// parseTimeParam reads an RFC3339 query param, falling back when it isn't set.
+func parseTimeParam(r *tsymbiote.HTTPRequest, name string, fallback time.Time) (time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// PeerMapSnapshots lists the stored snapshot times, optionally between ?since= and ?until=.
+func (t *TSymbioteUIServer) PeerMapSnapshots(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	since, err := parseTimeParam(r, "since", time.Time{})
+	if err != nil {
+		r.Log.Errorw("invalid since", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	until, err := parseTimeParam(r, "until", time.Now())
+	if err != nil {
+		r.Log.Errorw("invalid until", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	t.peerMapHistory.mu.Lock()
+	times := []time.Time{}
+	for _, snapshotTime := range t.peerMapHistory.times {
+		if !snapshotTime.Before(since) && !snapshotTime.After(until) {
+			times = append(times, snapshotTime)
+		}
+	}
+	t.peerMapHistory.mu.Unlock()
+
+	t.WriteJson(w, r, times)
+}
+
+// PeerMapSnapshot returns the peer map as of ?at=, the latest snapshot taken at or before it.
+func (t *TSymbioteUIServer) PeerMapSnapshot(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	at, err := parseTimeParam(r, "at", time.Now())
+	if err != nil {
+		r.Log.Errorw("invalid at", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	t.peerMapHistory.mu.Lock()
+	index, found := slices.BinarySearchFunc(t.peerMapHistory.times, at, time.Time.Compare)
+	if found {
+		index++
+	}
+	var snapshotTime time.Time
+	if index > 0 {
+		snapshotTime = t.peerMapHistory.times[index-1]
+	}
+	t.peerMapHistory.mu.Unlock()
+
+	if snapshotTime.IsZero() {
+		r.Log.Infow("no peer map snapshot before time", "at", at)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	snapshot, err := loadPeerMapSnapshot(snapshotTime)
+	if err != nil {
+		r.Log.Errorw("failed to load peer map snapshot", "time", snapshotTime, "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	t.WriteJson(w, r, snapshot)
+}
+
+// PeerMapChanges is a feed of what changed between consecutive snapshots from ?since= (default 24h ago) to ?until=.
+// ?node= only returns changes to that node and its edges, IE: when did node X stop seeing node Y.
+func (t *TSymbioteUIServer) PeerMapChanges(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	now := time.Now()
+	since, err := parseTimeParam(r, "since", now.Add(-defaultChangesWindow))
+	if err != nil {
+		r.Log.Errorw("invalid since", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	until, err := parseTimeParam(r, "until", now)
+	if err != nil {
+		r.Log.Errorw("invalid until", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	node := r.URL.Query().Get("node")
+
+	changes := []PeerMapChange{}
+	var previous *PeerMapSnapshot
+	for _, snapshotTime := range t.peerMapHistory.between(since, until) {
+		snapshot, err := loadPeerMapSnapshot(snapshotTime)
+		if err != nil {
+			// Expired while we were reading, skip it.
+			r.Log.Infow("failed to load peer map snapshot", "time", snapshotTime, "error", err)
+			continue
+		}
+
+		if previous != nil {
+			for _, change := range diffPeerMaps(previous, snapshot) {
+				if node == "" || change.ID == node || change.Source == node || change.Target == node {
+					changes = append(changes, change)
+				}
+			}
+		}
+		previous = snapshot
+	}
+
+	t.WriteJson(w, r, changes)
+}
+
+// diffPeerMaps compares two snapshots, edges are only compared when their source answered both times.
+func diffPeerMaps(before *PeerMapSnapshot, after *PeerMapSnapshot) []PeerMapChange {
+	var changes []PeerMapChange
+	change := func(kind string, id string, what string) PeerMapChange {
+		return PeerMapChange{Time: after.Time, Previous: before.Time, Kind: kind, ID: id, Change: what}
+	}
+
+	beforeNodes := map[string]bool{}
+	for _, node := range before.Nodes {
+		beforeNodes[node.ID] = true
+	}
+	afterNodes := map[string]bool{}
+	for _, node := range after.Nodes {
+		afterNodes[node.ID] = true
+		if !beforeNodes[node.ID] {
+			changes = append(changes, change("node", node.ID, "appeared"))
+		}
+	}
+	for _, node := range before.Nodes {
+		if !afterNodes[node.ID] {
+			changes = append(changes, change("node", node.ID, "disappeared"))
+		}
+	}
+
+	observedBoth := func(source string) bool {
+		return slices.Contains(before.Observers, source) && slices.Contains(after.Observers, source)
+	}
+
+	beforeEdges := map[string]Edge{}
+	for _, edge := range before.Edges {
+		beforeEdges[edge.ID] = edge
+	}
+	afterEdges := map[string]Edge{}
+	for _, edge := range after.Edges {
+		afterEdges[edge.ID] = edge
+		if !observedBoth(edge.Source) {
+			continue
+		}
+
+		edgeChange := change("edge", edge.ID, "")
+		edgeChange.Source = edge.Source
+		edgeChange.Target = edge.Target
+
+		previous, ok := beforeEdges[edge.ID]
+		switch {
+		case !ok:
+			edgeChange.Change = "appeared"
+			edgeChange.To = edge.Connection
+		case previous.Connection != edge.Connection:
+			edgeChange.Change = "connection"
+			edgeChange.From = previous.Connection
+			edgeChange.To = edge.Connection
+		default:
+			continue
+		}
+		changes = append(changes, edgeChange)
+	}
+	for _, edge := range before.Edges {
+		if _, ok := afterEdges[edge.ID]; ok || !observedBoth(edge.Source) {
+			continue
+		}
+		edgeChange := change("edge", edge.ID, "disappeared")
+		edgeChange.Source = edge.Source
+		edgeChange.Target = edge.Target
+		edgeChange.From = edge.Connection
+		changes = append(changes, edgeChange)
+	}
+
+	return changes
+}
+
+// startPeerMapHistory loads existing snapshots and starts snapshotting when --peermap-snapshot-interval is set.
+func (t *TSymbioteUIServer) startPeerMapHistory(interval time.Duration, retention time.Duration) error {
+	history, err := t.newPeerMapHistory(interval, retention)
+	if err != nil {
+		return err
+	}
+	t.peerMapHistory = history
+
+	if interval > 0 {
+		t.RunWSFunc(history.run)
+	}
+	return nil
+}
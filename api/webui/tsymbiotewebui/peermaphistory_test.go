@@ -0,0 +1,60 @@
+package tsymbiotewebui
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diffPeerMaps", func() {
+	first := time.Date(2025, time.January, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+
+	edge := func(source string, target string, connection string) Edge {
+		return Edge{ID: source + "-" + target, Source: source, Target: target, Connection: connection}
+	}
+	nodes := func(ids ...string) []Node {
+		out := []Node{}
+		for _, id := range ids {
+			out = append(out, Node{ID: id, Label: id})
+		}
+		return out
+	}
+
+	It("Should report nodes and edges that appeared, disappeared or changed connection", func() {
+		before := &PeerMapSnapshot{
+			Time:      first,
+			Observers: []string{"a", "c"},
+			NodeGraph: NodeGraph{
+				Nodes: nodes("a", "b", "x"),
+				Edges: []Edge{edge("a", "b", "direct"), edge("a", "x", "derp"), edge("c", "b", "direct")},
+			},
+		}
+		after := &PeerMapSnapshot{
+			Time:      second,
+			Observers: []string{"a"},
+			NodeGraph: NodeGraph{
+				Nodes: nodes("a", "b", "y"),
+				Edges: []Edge{edge("a", "b", "derp"), edge("a", "y", "direct")},
+			},
+		}
+
+		Expect(diffPeerMaps(before, after)).To(Equal([]PeerMapChange{
+			{Time: second, Previous: first, Kind: "node", ID: "y", Change: "appeared"},
+			{Time: second, Previous: first, Kind: "node", ID: "x", Change: "disappeared"},
+			{Time: second, Previous: first, Kind: "edge", ID: "a-b", Source: "a", Target: "b", Change: "connection", From: "direct", To: "derp"},
+			{Time: second, Previous: first, Kind: "edge", ID: "a-y", Source: "a", Target: "y", Change: "appeared", To: "direct"},
+			{Time: second, Previous: first, Kind: "edge", ID: "a-x", Source: "a", Target: "x", Change: "disappeared", From: "derp"},
+		}))
+	})
+
+	It("Should report nothing for identical snapshots", func() {
+		snapshot := &PeerMapSnapshot{
+			Time:      first,
+			Observers: []string{"a"},
+			NodeGraph: NodeGraph{Nodes: nodes("a", "b"), Edges: []Edge{edge("a", "b", "direct")}},
+		}
+		Expect(diffPeerMaps(snapshot, snapshot)).To(BeEmpty())
+	})
+})
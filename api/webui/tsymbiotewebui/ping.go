@@ -1,8 +1,8 @@
 package tsymbiotewebui
 
 import (
-	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"time"
 
@@ -15,9 +15,12 @@ import (
 type pingTargets struct {
 	Targets []string `json:"targets"`
 	Host    string   `json:"host"`
+	// Selector pings the targets from every matching host instead of a single Host.
+	Selector string `json:"selector,omitempty"`
 }
 
 type pingInput struct {
+	types.RequestOptions
 	Count    int           `json:"count"`
 	PingType string        `json:"pingType"`
 	Delay    string        `json:"delay"`
@@ -29,6 +32,8 @@ type pingResults struct {
 	Host    string           `json:"host,omitempty"`
 	Target  string           `json:"target,omitempty"`
 	Results []map[string]any `json:"results,omitempty"`
+	// Attempts made against the adapter, more than one when retried.
+	Attempts []types.Attempt `json:"attempts,omitempty"`
 }
 
 func (t *TSymbioteUIServer) Ping(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
@@ -48,11 +53,20 @@ func (t *TSymbioteUIServer) Ping(w http.ResponseWriter, r *tsymbiote.HTTPRequest
 		return
 	}
 
-	// (delay between pings * count) + default timeout
-	totalDelay := (time.Duration(input.Count) * delay) + consts.OutgoingRequestTimeout
+	input.Args, err = t.expandPingTargets(r, input.Args)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
 
-	outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(totalDelay))
-	defer outgoingcancel()
+	// (delay between pings * count) + request timeout
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, time.Duration(input.Count)*delay)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
 
 	var channels []chan pingResults
 	for _, pingTarget := range input.Args {
@@ -80,20 +94,14 @@ func (t *TSymbioteUIServer) Ping(w http.ResponseWriter, r *tsymbiote.HTTPRequest
 					return
 				}
 
-				resp, err := t.CallHost(outgoingctx, r, "POST", pingTarget.Host, paths.Ping.Adapter(), pingBody)
-				if err != nil {
-					r.Log.Errorw("failed to call adapter", "error", err)
-					result.Error = err.Error()
-					ch <- result
-					return
-				}
-
-				defer resp.Close()
-
-				pingRes := []map[string]any{}
-				err = json.NewDecoder(resp).Decode(&pingRes)
+				var pingRes []map[string]any
+				attempts, err := caller.CallHost(r.Context(), r, "POST", pingTarget.Host, paths.Ping.Adapter(), pingBody, func(resp io.Reader) error {
+					pingRes = []map[string]any{}
+					return json.NewDecoder(resp).Decode(&pingRes)
+				})
+				result.Attempts = attempts
 				if err != nil {
-					r.Log.Errorw("failed to decode ping response from adapter", "error", err)
+					r.Log.Errorw("failed to call adapter", "host", pingTarget.Host, "attempts", len(attempts), "error", err)
 					result.Error = err.Error()
 					ch <- result
 					return
@@ -105,12 +113,34 @@ func (t *TSymbioteUIServer) Ping(w http.ResponseWriter, r *tsymbiote.HTTPRequest
 		}
 	}
 
-	pingResults := []pingResults{}
-	for _, channel := range channels {
-		res := <-channel
-		close(channel)
-		pingResults = append(pingResults, res)
+	writeFanOut(t, w, r, channels, func(res pingResults) string { return res.Error })
+}
+
+// expandPingTargets replaces every selector arg with one arg per selected host.
+func (t *TSymbioteUIServer) expandPingTargets(r *tsymbiote.HTTPRequest, args []pingTargets) ([]pingTargets, error) {
+	resolver := t.newHostResolver(r)
+
+	expanded := make([]pingTargets, 0, len(args))
+	for _, arg := range args {
+		if arg.Selector == "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		var hosts []string
+		if arg.Host != "" {
+			hosts = []string{arg.Host}
+		}
+
+		hosts, err := resolver.Resolve(r.Context(), hosts, arg.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, host := range hosts {
+			expanded = append(expanded, pingTargets{Targets: arg.Targets, Host: host})
+		}
 	}
 
-	t.WriteJson(w, r, pingResults)
+	return expanded, nil
 }
@@ -1,7 +1,6 @@
 package tsymbiotewebui
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,7 +19,8 @@ type pprofResult struct {
 	Error string `json:"error,omitempty"`
 	Host  string `json:"hosts"`
 	Type  string `json:"type"`
-	Pprof []byte `json:"pprof"`
+	// Attempts made against the adapter, more than one when retried.
+	Attempts []types.Attempt `json:"attempts,omitempty"`
 }
 
 func (t *TSymbioteUIServer) Pprof(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
@@ -32,9 +32,21 @@ func (t *TSymbioteUIServer) Pprof(w http.ResponseWriter, r *tsymbiote.HTTPReques
 		r.SetStatusCode(w, http.StatusInternalServerError)
 		return
 	}
-	deadlineTimeout := time.Duration(int64(input.Seconds))*time.Second + consts.OutgoingRequestTimeout
-	outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(deadlineTimeout))
-	defer outgoingcancel()
+
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	// Profiles take the requested seconds on top of the request timeout.
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, time.Duration(int64(input.Seconds))*time.Second)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
 
 	var channels []chan pprofResult
 	for _, targetHost := range input.Hosts {
@@ -61,50 +73,32 @@ func (t *TSymbioteUIServer) Pprof(w http.ResponseWriter, r *tsymbiote.HTTPReques
 				return
 			}
 
-			resp, err := t.CallHost(outgoingctx, r, "POST", targetHost, paths.Pprof.Adapter(), pprofBody)
+			var pprofRes []byte
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.Pprof.Adapter(), pprofBody, func(resp io.Reader) error {
+				var readErr error
+				pprofRes, readErr = io.ReadAll(resp)
+				return readErr
+			})
+			result.Attempts = attempts
 			if err != nil {
-				r.Log.Errorw("failed to call adapter", "error", err)
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
 				result.Error = err.Error()
 				ch <- result
 				return
 			}
 
-			defer resp.Close()
-
-			pprofRes, err := io.ReadAll(resp)
+			// Profiles are served from disk, don't send the raw bytes back to the client.
+			err = writePprofToFile(targetHost, pprofRes)
 			if err != nil {
-				r.Log.Errorw("failed to read response body", "error", err)
+				r.Log.Errorw("failed to write pprof to file", "error", err)
 				result.Error = err.Error()
-				ch <- result
-				return
 			}
 
-			result.Pprof = pprofRes
 			ch <- result
 		}()
 	}
 
-	pprofResp := []pprofResult{}
-	for _, channel := range channels {
-		res := <-channel
-		close(channel)
-		tmpRes := pprofResult{
-			Error: res.Error,
-			Host:  res.Host,
-		}
-
-		if res.Error == "" {
-			err = writePprofToFile(res.Host, res.Pprof)
-			if err != nil {
-				r.Log.Errorw("failed to write pprof to file", "error", err)
-				res.Error = err.Error()
-			}
-		}
-
-		pprofResp = append(pprofResp, tmpRes)
-	}
-
-	t.WriteJson(w, r, pprofResp)
+	writeFanOut(t, w, r, channels, func(res pprofResult) string { return res.Error })
 }
 
 func writePprofToFile(host string, data []byte) error {
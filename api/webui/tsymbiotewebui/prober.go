@@ -0,0 +1,290 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+)
+
+const proberUser = "tsymbiote-prober"
+
+// metricsTag is the tag a Prometheus scraper needs to read /metrics.
+const metricsTag = "tag:tsymbiote-metrics"
+
+var (
+	probeLabels  = []string{"source", "target", "derp_region"}
+	probeLatency = prometheus.NewDesc("tsymbiote_ping_latency_seconds",
+		"Latency of the last disco ping from source to target.", probeLabels, nil)
+	probeSuccess = prometheus.NewDesc("tsymbiote_ping_success",
+		"1 if the last disco ping from source to target got a reply.", probeLabels, nil)
+	probeRelayed = prometheus.NewDesc("tsymbiote_path_relayed",
+		"1 if the last disco ping went through a DERP or peer relay instead of a direct path.", probeLabels, nil)
+)
+
+// metricsAuth only lets nodes tagged with metricsTag scrape.
+func (t *TSymbioteUIServer) metricsAuth(next tsymbiote.HandlerFunc) tsymbiote.HandlerFunc {
+	return func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+		resp, err := t.Local().WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			r.Log.Errorw("failed to get whois", "error", err)
+			r.SetStatusCode(w, http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(resp.Node.Tags, metricsTag) {
+			r.Log.Errorw("scrape rejected, missing metrics tag", "node", resp.Node.ComputedName)
+			r.SetStatusCode(w, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// probePair is a single source host pinging a target.
+type probePair struct {
+	Source string
+	Target string
+}
+
+type probeResult struct {
+	probePair
+	Success bool
+	Relayed bool
+	Latency float64
+	// Region is the DERP region code, empty when the path was direct.
+	Region string
+}
+
+// prober pings host pairs on an interval and exports the last round as Prometheus gauges.
+// Pairs come from --probe-pairs, or every ordered pair of hosts matching --probe-selector.
+type prober struct {
+	*TSymbioteUIServer
+	interval time.Duration
+	pairs    []probePair
+	selector string
+
+	mu      sync.Mutex
+	results []probeResult
+}
+
+func (t *TSymbioteUIServer) newProber(interval time.Duration, rawPairs []string, selector string) (*prober, error) {
+	p := &prober{
+		TSymbioteUIServer: t,
+		interval:          interval,
+		selector:          selector,
+	}
+
+	for _, raw := range rawPairs {
+		source, target, ok := strings.Cut(raw, ":")
+		if !ok || source == "" || target == "" {
+			return nil, fmt.Errorf("probe pairs must be source:target, got %q", raw)
+		}
+		p.pairs = append(p.pairs, probePair{Source: source, Target: target})
+	}
+
+	if len(p.pairs) == 0 {
+		_, err := types.ParseHostSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probe selector: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// Describe implements prometheus.Collector.
+func (p *prober) Describe(ch chan<- *prometheus.Desc) {
+	ch <- probeLatency
+	ch <- probeSuccess
+	ch <- probeRelayed
+}
+
+// Collect implements prometheus.Collector, only the last completed round is exported so removed pairs go stale.
+func (p *prober) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	results := p.results
+	p.mu.Unlock()
+
+	for _, result := range results {
+		labels := []string{result.Source, result.Target, result.Region}
+		ch <- prometheus.MustNewConstMetric(probeSuccess, prometheus.GaugeValue, boolGauge(result.Success), labels...)
+		// Failed pings have no path to report on.
+		if !result.Success {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(probeLatency, prometheus.GaugeValue, result.Latency, labels...)
+		ch <- prometheus.MustNewConstMetric(probeRelayed, prometheus.GaugeValue, boolGauge(result.Relayed), labels...)
+	}
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// run probes every interval until shutdown, a round that overruns the interval delays the next one.
+func (p *prober) run(shutdownCtx context.Context) {
+	p.Log.Infow("starting ping prober", "interval", p.interval, "pairs", len(p.pairs), "selector", p.selector)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.probe(shutdownCtx)
+
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe runs a single round and swaps in its results.
+func (p *prober) probe(ctx context.Context) {
+	r, err := p.backgroundRequest(ctx, proberUser)
+	if err != nil {
+		p.Log.Errorw("failed to build probe request", "error", err)
+		return
+	}
+
+	pairs, err := p.roundPairs(ctx, r)
+	if err != nil {
+		r.Log.Errorw("failed to list probe pairs", "error", err)
+		return
+	}
+
+	caller, err := p.newFanOutCaller(types.RequestOptions{}, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("failed to create probe caller", "error", err)
+		return
+	}
+
+	results := make([]probeResult, len(pairs))
+	var wg sync.WaitGroup
+	for i, pair := range pairs {
+		wg.Go(func() {
+			results[i] = p.ping(ctx, r, caller, pair)
+		})
+	}
+	wg.Wait()
+
+	// Keep the last full round rather than exporting a partial one.
+	if ctx.Err() != nil {
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	r.Log.Infow("probe round finished", "pairs", len(results), "failed", failed)
+
+	p.mu.Lock()
+	p.results = results
+	p.mu.Unlock()
+}
+
+// roundPairs returns the configured pairs, or the full mesh of selected hosts.
+func (p *prober) roundPairs(ctx context.Context, r *tsymbiote.HTTPRequest) ([]probePair, error) {
+	if len(p.pairs) > 0 {
+		return p.pairs, nil
+	}
+
+	hosts, err := p.newHostResolver(r).Resolve(ctx, nil, p.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []probePair
+	for _, source := range hosts {
+		for _, target := range hosts {
+			if source != target {
+				pairs = append(pairs, probePair{Source: source, Target: target})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// ping sends a single disco ping through the source's adapter.
+func (p *prober) ping(ctx context.Context, r *tsymbiote.HTTPRequest, caller *fanOutCaller, pair probePair) probeResult {
+	result := probeResult{probePair: pair}
+
+	body, err := json.Marshal(&types.PingInput{
+		Target:   pair.Target,
+		Count:    1,
+		PingType: "disco",
+		Delay:    "0s",
+	})
+	if err != nil {
+		r.Log.Errorw("failed to marshal ping input", "error", err)
+		return result
+	}
+
+	// The adapter returns ipnstate.PingResult, failed pings are left out entirely.
+	var pings []struct {
+		Err            string
+		LatencySeconds float64
+		DERPRegionCode string
+		PeerRelay      string
+	}
+	_, err = caller.CallHost(ctx, r, "POST", pair.Source, paths.Ping.Adapter(), body, func(resp io.Reader) error {
+		return json.NewDecoder(resp).Decode(&pings)
+	})
+	if err != nil {
+		r.Log.Infow("probe failed", "source", pair.Source, "target", pair.Target, "error", err)
+		return result
+	}
+
+	if len(pings) == 0 || pings[0].Err != "" {
+		return result
+	}
+
+	ping := pings[0]
+	result.Success = true
+	result.Latency = ping.LatencySeconds
+	result.Region = ping.DERPRegionCode
+	result.Relayed = ping.DERPRegionCode != "" || ping.PeerRelay != ""
+	return result
+}
+
+// startProber registers the prober metrics and starts probing when --probe-interval is set.
+func (t *TSymbioteUIServer) startProber(registry prometheus.Registerer) error {
+	interval := viper.GetDuration("probe-interval")
+	if interval <= 0 {
+		return nil
+	}
+
+	p, err := t.newProber(interval, viper.GetStringSlice("probe-pairs"), viper.GetString("probe-selector"))
+	if err != nil {
+		return err
+	}
+
+	err = registry.Register(p)
+	if err != nil {
+		return err
+	}
+
+	t.RunWSFunc(p.run)
+	return nil
+}
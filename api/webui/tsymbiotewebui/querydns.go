@@ -1,10 +1,9 @@
 package tsymbiotewebui
 
 import (
-	"context"
 	"encoding/json"
+	"io"
 	"net/http"
-	"time"
 
 	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
@@ -22,8 +21,19 @@ func (t *TSymbioteUIServer) QueryDNS(w http.ResponseWriter, r *tsymbiote.HTTPReq
 		return
 	}
 
-	outgoingctx, outgoingcancel := context.WithDeadline(r.Context(), time.Now().Add(consts.OutgoingRequestTimeout))
-	defer outgoingcancel()
+	input.Hosts, err = t.resolveHosts(r, input.Hosts, input.Selector)
+	if err != nil {
+		r.Log.Errorw("failed to resolve hosts", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	caller, err := t.newFanOutCaller(input.RequestOptions, consts.OutgoingRequestTimeout, 0)
+	if err != nil {
+		r.Log.Errorw("invalid request options", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
 
 	var channels []chan types.QueryDNSResult
 	for _, targetHost := range input.Hosts {
@@ -49,22 +59,13 @@ func (t *TSymbioteUIServer) QueryDNS(w http.ResponseWriter, r *tsymbiote.HTTPReq
 				return
 			}
 
-			outgoingctx, outgoingcancel := context.WithDeadline(outgoingctx, time.Now().Add(consts.OutgoingRequestTimeout))
-			defer outgoingcancel()
-
-			resp, err := t.CallHost(outgoingctx, r, "POST", targetHost, paths.QueryDNS.Adapter(), queryDNSCommandBody)
-			if err != nil {
-				r.Log.Errorw("failed to call adapter", "error", err)
-				result.Error = err.Error()
-				ch <- result
-				return
-			}
-
-			defer resp.Close()
-
-			err = json.NewDecoder(resp).Decode(&result)
+			attempts, err := caller.CallHost(r.Context(), r, "POST", targetHost, paths.QueryDNS.Adapter(), queryDNSCommandBody, func(resp io.Reader) error {
+				result = types.QueryDNSResult{Host: targetHost}
+				return json.NewDecoder(resp).Decode(&result)
+			})
+			result.Attempts = attempts
 			if err != nil {
-				r.Log.Errorw("failed to decode querydns response from adapter", "error", err)
+				r.Log.Errorw("failed to call adapter", "host", targetHost, "attempts", len(attempts), "error", err)
 				result.Error = err.Error()
 				ch <- result
 				return
@@ -74,12 +75,5 @@ func (t *TSymbioteUIServer) QueryDNS(w http.ResponseWriter, r *tsymbiote.HTTPReq
 		}()
 	}
 
-	queryDNSResults := []types.QueryDNSResult{}
-	for _, channel := range channels {
-		res := <-channel
-		close(channel)
-		queryDNSResults = append(queryDNSResults, res)
-	}
-
-	t.WriteJson(w, r, queryDNSResults)
+	writeFanOut(t, w, r, channels, func(res types.QueryDNSResult) string { return res.Error })
 }
@@ -0,0 +1,441 @@
+package tsymbiotewebui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+const recordingsPath = "/tmp/TSymbiote/recordings/"
+
+var recordingIDRegex = regexp.MustCompile("^[a-zA-Z0-9-]+$")
+
+// openRecordings holds the IDs of recordings still being written, pruning leaves them alone however quiet they are.
+var openRecordings sync.Map
+
+// RecordingHeader is the first line of every recording file, the remaining lines are tsymbiote.WebsocketRecord.
+type RecordingHeader struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name,omitempty"`
+	Kind    string    `json:"kind"`
+	Hosts   []string  `json:"hosts"`
+	User    string    `json:"user,omitempty"`
+	TraceID string    `json:"traceId,omitempty"`
+	Started time.Time `json:"started"`
+	// Ended is taken from the file modification time when listing.
+	Ended time.Time `json:"ended,omitzero"`
+}
+
+// wsRecorder writes client bound websocket messages to disk as newline delimited json.
+// It is only used from a single goroutine so it does no locking.
+type wsRecorder struct {
+	id     string
+	file   *os.File
+	writer *bufio.Writer
+	enc    *json.Encoder
+	start  time.Time
+}
+
+func newWSRecorder(r *tsymbiote.HTTPRequest, header RecordingHeader) (*wsRecorder, error) {
+	err := os.MkdirAll(recordingsPath, 0770)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every new recording is a good time to drop the expired ones.
+	pruneRecordings(r, viper.GetDuration("recording-retention"))
+
+	file, err := os.Create(recordingFile(header.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	recorder := &wsRecorder{
+		id:     header.ID,
+		file:   file,
+		writer: writer,
+		enc:    json.NewEncoder(writer),
+		start:  header.Started,
+	}
+
+	err = recorder.enc.Encode(header)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	openRecordings.Store(header.ID, true)
+	return recorder, nil
+}
+
+func (w *wsRecorder) Record(msg tsymbiote.WebsocketMessage) error {
+	return w.enc.Encode(tsymbiote.WebsocketRecord{
+		Offset:  time.Since(w.start),
+		Type:    msg.Type,
+		Message: msg.Message,
+	})
+}
+
+func (w *wsRecorder) Close() error {
+	defer openRecordings.Delete(w.id)
+
+	err := w.writer.Flush()
+	if err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// pruneRecordings removes recordings last written before the retention period, 0 keeps them forever.
+// Recordings that are still open are kept.
+func pruneRecordings(r *tsymbiote.HTTPRequest, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(recordingsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.Log.Errorw("failed to read recordings directory", "error", err)
+		}
+		return
+	}
+
+	cutoff := time.Now().Add(-retention)
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".ndjson")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, open := openRecordings.Load(id); open {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(recordingsPath, entry.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			r.Log.Errorw("failed to remove expired recording", "recording", entry.Name(), "error", err)
+		}
+	}
+}
+
+func newRecordingID() string {
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405Z"), utils.RandomString(6))
+}
+
+func recordingFile(id string) string {
+	return filepath.Join(recordingsPath, fmt.Sprintf("%s.ndjson", id))
+}
+
+// openRecording validates the id and returns the file along with the decoded header.
+func openRecording(id string) (*os.File, *bufio.Reader, *RecordingHeader, error) {
+	if !recordingIDRegex.MatchString(id) {
+		return nil, nil, nil, errors.New("invalid recording id")
+	}
+
+	file, err := os.Open(recordingFile(id))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	header, err := readRecordingHeader(reader)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+
+	return file, reader, header, nil
+}
+
+func readRecordingHeader(reader *bufio.Reader) (*RecordingHeader, error) {
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %w", err)
+	}
+
+	header := &RecordingHeader{}
+	err = json.Unmarshal(line, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode recording header: %w", err)
+	}
+	return header, nil
+}
+
+// Recordings lists the recordings stored by the WebUI, newest first.
+func (t *TSymbioteUIServer) Recordings(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	pruneRecordings(r, viper.GetDuration("recording-retention"))
+
+	entries, err := os.ReadDir(recordingsPath)
+	if err != nil && !os.IsNotExist(err) {
+		r.Log.Errorw("failed to read recordings directory", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	recordings := []RecordingHeader{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".ndjson")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		file, _, header, err := openRecording(id)
+		if err != nil {
+			r.Log.Errorw("failed to read recording", "recording", id, "error", err)
+			continue
+		}
+		file.Close()
+
+		info, err := entry.Info()
+		if err == nil {
+			header.Ended = info.ModTime()
+		}
+
+		recordings = append(recordings, *header)
+	}
+
+	slices.SortFunc(recordings, func(a, b RecordingHeader) int {
+		return b.Started.Compare(a.Started)
+	})
+
+	t.WriteJson(w, r, recordings)
+}
+
+// DownloadRecording returns the raw recording file so it can be attached to a ticket.
+func (t *TSymbioteUIServer) DownloadRecording(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	id := r.PathValue("id")
+
+	file, _, _, err := openRecording(id)
+	if err != nil {
+		r.Log.Errorw("failed to open recording", "recording", id, "error", err)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		r.Log.Errorw("failed to seek recording", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(file.Name())))
+	_, err = io.Copy(w, file)
+	if err != nil {
+		r.Log.Errorw("failed to write recording", "error", err)
+	}
+}
+
+// UploadRecording stores a previously downloaded recording under a new ID so it can be replayed.
+// Uploads are capped at MaxRecordingUploadSize and every line at MaxRecordingLineSize.
+func (t *TSymbioteUIServer) UploadRecording(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, consts.MaxRecordingUploadSize))
+	scanner.Buffer(make([]byte, 0, 64*1024), consts.MaxRecordingLineSize)
+
+	uploadStatus := func(err error) int {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) || errors.Is(err, bufio.ErrTooLong) {
+			return http.StatusRequestEntityTooLarge
+		}
+		return http.StatusBadRequest
+	}
+
+	if !scanner.Scan() {
+		err := scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		r.Log.Errorw("failed to read uploaded recording", "error", err)
+		r.SetStatusCode(w, uploadStatus(err))
+		return
+	}
+
+	header := &RecordingHeader{}
+	err := json.Unmarshal(scanner.Bytes(), header)
+	if err != nil {
+		r.Log.Errorw("failed to decode uploaded recording header", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	// Always assign a fresh ID, never trust one from a file.
+	header.ID = newRecordingID()
+
+	recorder, err := newWSRecorder(r, *header)
+	if err != nil {
+		r.Log.Errorw("failed to create recording", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := tsymbiote.WebsocketRecord{}
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err == nil {
+			err = recorder.enc.Encode(record)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+	if err != nil {
+		r.Log.Errorw("failed to copy uploaded recording", "error", err)
+		recorder.Close()
+		os.Remove(recordingFile(header.ID))
+		r.SetStatusCode(w, uploadStatus(err))
+		return
+	}
+
+	err = recorder.Close()
+	if err != nil {
+		r.Log.Errorw("failed to save recording", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	t.WriteJson(w, r, header)
+}
+
+// Replay streams a recording back over a websocket using the same message shape as RelativeWebsocket.
+// Stream subscriptions are recorded in the same shape, see startRecording.
+// Query params: id is the recording, speed is a playback multiplier where 0 means as fast as possible.
+func (t *TSymbioteUIServer) Replay(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	urlparams := r.URL.Query()
+
+	speed := 1.0
+	if rawSpeed := urlparams.Get("speed"); rawSpeed != "" {
+		parsed, err := strconv.ParseFloat(rawSpeed, 64)
+		if err != nil || parsed < 0 {
+			r.Log.Errorw("invalid replay speed", "speed", rawSpeed)
+			r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseUnsupportedData, "invalid speed"), time.Now().Add(consts.WSWriteTimeout))
+			r.WS.Close()
+			return
+		}
+		speed = parsed
+	}
+
+	file, reader, header, err := openRecording(urlparams.Get("id"))
+	if err != nil {
+		r.Log.Errorw("failed to open recording", "error", err)
+		r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "recording not found"), time.Now().Add(consts.WSWriteTimeout))
+		r.WS.Close()
+		return
+	}
+
+	r.Log.Infow("replaying recording", "recording", header.ID, "kind", header.Kind, "speed", speed)
+
+	// Buffered so the client reader can't block on a pong after the writer has finished.
+	msg := make(chan tsymbiote.WebsocketMessage, 1)
+
+	// Used to signal kill across routines
+	clientDeathCtx, deathFunc := context.WithCancel(context.Background())
+
+	t.RunWSFunc(clientWebsocketReader(clientDeathCtx, deathFunc, r, msg))
+	t.RunWSFunc(replayWebsocketWriter(clientDeathCtx, deathFunc, r, file, reader, speed, msg))
+}
+
+func replayWebsocketWriter(clientDeathCtx context.Context, deathFunc func(), r *tsymbiote.HTTPRequest, file *os.File, reader *bufio.Reader, speed float64, msg chan tsymbiote.WebsocketMessage) tsymbiote.WebsocketFunc {
+	return func(writerFuncCtx context.Context) {
+		defer file.Close()
+		defer deathFunc()
+
+		closeClientFunc := func() {
+			err := r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
+			if err != nil {
+				tsymbiote.LogWebsocketError(r, err)
+			}
+		}
+
+		writeFunc := func(message tsymbiote.WebsocketMessage) bool {
+			r.WS.SetWriteDeadline(time.Now().Add(consts.WSWriteTimeout))
+			err := r.WS.WriteMessage(message.Type, message.Message)
+			if err != nil {
+				tsymbiote.LogWebsocketError(r, err)
+				deathFunc()
+				return false
+			}
+			return true
+		}
+
+		decoder := json.NewDecoder(reader)
+		var lastOffset time.Duration
+
+		for {
+			record := tsymbiote.WebsocketRecord{}
+			err := decoder.Decode(&record)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					r.Log.Errorw("failed to decode recording", "error", err)
+				}
+				closeClientFunc()
+				return
+			}
+
+			wait := time.Duration(0)
+			if speed > 0 {
+				wait = time.Duration(float64(record.Offset-lastOffset) / speed)
+			}
+			lastOffset = record.Offset
+
+			timer := time.NewTimer(wait)
+			waiting := true
+			for waiting {
+				select {
+				// Server shutdown, close the client.
+				case <-writerFuncCtx.Done():
+					timer.Stop()
+					closeClientFunc()
+					return
+				// Client died in read loop, nothing left to do.
+				case <-clientDeathCtx.Done():
+					timer.Stop()
+					return
+				// Keep answering the client pings while waiting on the next frame.
+				case pong := <-msg:
+					if !writeFunc(pong) {
+						timer.Stop()
+						return
+					}
+				case <-timer.C:
+					waiting = false
+				}
+			}
+
+			if !writeFunc(tsymbiote.WebsocketMessage{Type: record.Type, Message: record.Message}) {
+				return
+			}
+		}
+	}
+}
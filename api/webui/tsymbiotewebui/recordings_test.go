@@ -0,0 +1,36 @@
+package tsymbiotewebui
+
+import (
+	"os"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("pruneRecordings", func() {
+	It("Should keep recordings that are still being written however quiet they are", func() {
+		r := &tsymbiote.HTTPRequest{Log: zap.NewNop().Sugar()}
+		header := RecordingHeader{ID: newRecordingID(), Kind: "logs", Started: time.Now()}
+
+		recorder, err := newWSRecorder(r, header)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			os.Remove(recordingFile(header.ID))
+		})
+
+		old := time.Now().Add(-time.Hour * 2)
+		Expect(os.Chtimes(recordingFile(header.ID), old, old)).To(Succeed())
+
+		pruneRecordings(r, time.Hour)
+		Expect(recordingFile(header.ID)).To(BeAnExistingFile())
+
+		Expect(recorder.Close()).To(Succeed())
+		Expect(os.Chtimes(recordingFile(header.ID), old, old)).To(Succeed())
+
+		pruneRecordings(r, time.Hour)
+		Expect(recordingFile(header.ID)).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,150 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/api/webui/client"
+	"tailscale.com/client/tailscale/v2"
+)
+
+const relayTag = "tag:tsymbiote-relay"
+
+// adapterListing is every adapter we can call along with what we learned finding them.
+type adapterListing struct {
+	Adapters    []string
+	HostDevices map[string]tailscale.Device
+
+	// devices names every adapter and relay device in the tailnet.
+	// listed names the relays and adapters that answered when asked what sits behind them.
+	devices map[string]bool
+	listed  map[string]bool
+}
+
+// gone reports whether a known adapter has left, rather than sitting behind a relay or adapter that didn't answer this time.
+func (l *adapterListing) gone(adapter string) bool {
+	if slices.Contains(l.Adapters, adapter) {
+		return false
+	}
+
+	device, _ := client.SplitAdapter(adapter)
+	if !l.devices[device] {
+		return true
+	}
+
+	for _, parent := range adapterParents(adapter) {
+		if !l.listed[parent] {
+			return false
+		}
+	}
+	return true
+}
+
+// adapterParents returns the relays and adapters that had to be asked to find adapter, outermost first.
+func adapterParents(adapter string) []string {
+	var parents []string
+	for i := range adapter {
+		rest := adapter[i:]
+		if strings.HasPrefix(rest, consts.RelayPathPrefix) || strings.HasPrefix(rest, consts.InstancePathPrefix) {
+			parents = append(parents, adapter[:i])
+		}
+	}
+	return parents
+}
+
+// listAdapters names every adapter we can call, adapters behind a relay are named <relay>/relay/<target>.
+// Adapters serving several tailscaled are split into their instances, see instanceAdapters.
+// The remaining devices are returned by hostname so callers can look up a host's tags.
+func (t *TSymbioteUIServer) listAdapters(ctx context.Context, r *tsymbiote.HTTPRequest) ([]string, map[string]tailscale.Device, error) {
+	listing, err := t.resolveAdapters(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listing.Adapters, listing.HostDevices, nil
+}
+
+// resolveAdapters is listAdapters, keeping track of which relays and adapters answered.
+func (t *TSymbioteUIServer) resolveAdapters(ctx context.Context, r *tsymbiote.HTTPRequest) (*adapterListing, error) {
+	devices, err := t.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &adapterListing{
+		HostDevices: map[string]tailscale.Device{},
+		devices:     map[string]bool{},
+		listed:      map[string]bool{},
+	}
+
+	var adapters []string
+	var relays []string
+	for _, device := range devices {
+		switch {
+		case slices.Contains(device.Tags, adapterTag):
+			adapters = append(adapters, device.Hostname)
+			listing.devices[device.Hostname] = true
+		case slices.Contains(device.Tags, relayTag):
+			relays = append(relays, device.Hostname)
+			listing.devices[device.Hostname] = true
+		default:
+			listing.HostDevices[device.Hostname] = device
+		}
+	}
+
+	relayed, listedRelays := t.relayAdapters(ctx, r, relays)
+	adapters = append(adapters, relayed...)
+
+	var listedAdapters []string
+	listing.Adapters, listedAdapters = t.instanceAdapters(ctx, r, adapters)
+
+	for _, name := range append(listedRelays, listedAdapters...) {
+		listing.listed[name] = true
+	}
+	return listing, nil
+}
+
+// relayAdapters asks every relay for the adapters behind it, a relay we can't reach contributes nothing.
+// The relays that answered are returned alongside.
+func (t *TSymbioteUIServer) relayAdapters(ctx context.Context, r *tsymbiote.HTTPRequest, relays []string) ([]string, []string) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	adapters := []string{}
+	listed := []string{}
+	for _, relay := range relays {
+		wg.Go(func() {
+			outgoingctx, outgoingcancel := context.WithTimeout(ctx, consts.OutgoingRequestTimeout)
+			defer outgoingcancel()
+
+			resp, err := t.CallAdapter(outgoingctx, r, "POST", relay, paths.Relay.Adapter(), nil)
+			if err != nil {
+				r.Log.Infow("failed to list relay targets", "relay", relay, "error", err)
+				return
+			}
+			defer resp.Close()
+
+			var targets []types.RelayTarget
+			err = json.NewDecoder(resp).Decode(&targets)
+			if err != nil {
+				r.Log.Infow("failed to decode relay targets", "relay", relay, "error", err)
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, target := range targets {
+				adapters = append(adapters, relay+consts.RelayPathPrefix+target.Name)
+			}
+			listed = append(listed, relay)
+		})
+	}
+	wg.Wait()
+
+	return adapters, listed
+}
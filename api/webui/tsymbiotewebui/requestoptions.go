@@ -0,0 +1,211 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/api/webui/client"
+	"github.com/dhouti/tsymbiote/pkg/utils"
+)
+
+var errInvalidOptions = errors.New("invalid request options")
+
+// retryPaths are the adapter endpoints that are safe to call again, retries are only made against these.
+// Anything else is called once whatever the request asks for, IE: every BugReport call files a report with Tailscale.
+var retryPaths = map[string]bool{
+	paths.Status.Adapter():        true,
+	paths.Prefs.Adapter():         true,
+	paths.DriveShares.Adapter():   true,
+	paths.DNSConfig.Adapter():     true,
+	paths.ServeConfig.Adapter():   true,
+	paths.AppConnRoutes.Adapter(): true,
+	paths.Goroutines.Adapter():    true,
+	paths.QueryDNS.Adapter():      true,
+	paths.DERPMap.Adapter():       true,
+	paths.PacketFilter.Adapter():  true,
+	paths.TailnetLock.Adapter():   true,
+}
+
+// requestLimits are the server side maximums for types.RequestOptions, set with the max-request-* flags.
+type requestLimits struct {
+	Timeout     time.Duration
+	Retries     int
+	Parallelism int
+}
+
+// fanOutCaller applies the request options to every adapter call of a single fan-out request.
+type fanOutCaller struct {
+	*client.Client
+	// timeout bounds each call, retries included.
+	timeout time.Duration
+	retries int
+	// slots bounds how many adapters are called at once.
+	slots chan struct{}
+}
+
+// newFanOutCaller validates the request options and clamps them to the server limits.
+// defaultTimeout is used when the request doesn't set one.
+// extra is added on top of the timeout for calls expected to take a while, IE: ping count * delay.
+func (t *TSymbioteUIServer) newFanOutCaller(opts types.RequestOptions, defaultTimeout time.Duration, extra time.Duration) (*fanOutCaller, error) {
+	timeout := defaultTimeout
+	if opts.Timeout != "" {
+		parsed, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		if parsed <= 0 {
+			return nil, fmt.Errorf("timeout must be positive: %s", opts.Timeout)
+		}
+		timeout = parsed
+	}
+
+	if opts.Retries < 0 || opts.Parallelism < 0 {
+		return nil, errors.New("retries and parallelism can't be negative")
+	}
+
+	parallelism := t.limits.Parallelism
+	if opts.Parallelism > 0 {
+		parallelism = min(opts.Parallelism, t.limits.Parallelism)
+	}
+
+	return &fanOutCaller{
+		Client:  t.Client,
+		timeout: min(timeout, t.limits.Timeout) + extra,
+		retries: min(opts.Retries, t.limits.Retries),
+		slots:   make(chan struct{}, max(parallelism, 1)),
+	}, nil
+}
+
+// requestOptionsFromQuery reads the request options from query params for GET handlers.
+func requestOptionsFromQuery(values url.Values) (types.RequestOptions, error) {
+	opts := types.RequestOptions{
+		Timeout: values.Get("timeout"),
+	}
+
+	var err error
+	if raw := values.Get("retries"); raw != "" {
+		opts.Retries, err = strconv.Atoi(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid retries: %w", err)
+		}
+	}
+
+	if raw := values.Get("parallelism"); raw != "" {
+		opts.Parallelism, err = strconv.Atoi(raw)
+		if err != nil {
+			return opts, fmt.Errorf("invalid parallelism: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// CallHost calls an adapter by its host with the request options applied.
+// handle reads the response, an error from it fails the attempt so it will be retried as well.
+func (f *fanOutCaller) CallHost(ctx context.Context, r *tsymbiote.HTTPRequest, method string, host string, path string, body []byte, handle func(io.Reader) error) ([]types.Attempt, error) {
+	return f.call(ctx, func(ctx context.Context) (io.ReadCloser, error) {
+		return f.Client.CallHost(ctx, r, method, host, path, body)
+	}, host, f.retriesFor(path), handle)
+}
+
+// CallAdapter calls an adapter by its own hostname with the request options applied.
+func (f *fanOutCaller) CallAdapter(ctx context.Context, r *tsymbiote.HTTPRequest, method string, adapter string, path string, body []byte, handle func(io.Reader) error) ([]types.Attempt, error) {
+	return f.call(ctx, func(ctx context.Context) (io.ReadCloser, error) {
+		return f.Client.CallAdapter(ctx, r, method, adapter, path, body)
+	}, adapter, f.retriesFor(path), handle)
+}
+
+// retriesFor returns how many retries a call to path may make, see retryPaths.
+func (f *fanOutCaller) retriesFor(path string) int {
+	if !retryPaths[path] {
+		return 0
+	}
+	return f.retries
+}
+
+// call waits for a free slot then makes up to retries + 1 attempts within the timeout, backing off between them.
+// Calls made for a job also wait on a slot for the host they're calling, see withJobManager.
+// Every attempt is returned so clients can see where the time went.
+func (f *fanOutCaller) call(ctx context.Context, do func(context.Context) (io.ReadCloser, error), host string, retries int, handle func(io.Reader) error) ([]types.Attempt, error) {
+	if jobs, ok := jobManagerFrom(ctx); ok {
+		release, err := jobs.acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-f.slots }()
+
+	// Waiting on a slot doesn't count, retries only get whatever is left of the timeout.
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	var attempts []types.Attempt
+	var err error
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(utils.Backoff(attempt-1, consts.RetryBaseDelay, consts.RetryMaxDelay))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return attempts, err
+			case <-timer.C:
+			}
+		}
+
+		start := time.Now()
+		err = attemptCall(ctx, do, handle)
+		record := types.Attempt{Duration: time.Since(start)}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		attempts = append(attempts, record)
+
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	return attempts, err
+}
+
+func attemptCall(ctx context.Context, do func(context.Context) (io.ReadCloser, error), handle func(io.Reader) error) error {
+	resp, err := do(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	return handle(resp)
+}
+
+// retryable reports whether an attempt failed for a reason that might go away, IE: timeouts or a restarting adapter.
+// Unknown hosts and client errors from the adapter will fail the same way every time.
+func retryable(err error) bool {
+	if errors.Is(err, client.ErrUnknownHost) {
+		return false
+	}
+
+	statusErr := &client.StatusError{}
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= http.StatusInternalServerError || statusErr.Code == http.StatusTooManyRequests
+	}
+
+	return true
+}
@@ -0,0 +1,36 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fanOutCaller", func() {
+	It("Should only retry endpoints that are safe to call again", func() {
+		caller := &fanOutCaller{retries: 3}
+		Expect(caller.retriesFor(paths.Status.Adapter())).To(Equal(3))
+		Expect(caller.retriesFor(paths.BugReport.Adapter())).To(Equal(0))
+		Expect(caller.retriesFor(paths.Pprof.Adapter())).To(Equal(0))
+		Expect(caller.retriesFor(paths.Ping.Adapter())).To(Equal(0))
+	})
+
+	It("Should stop retrying once the timeout is spent", func() {
+		caller := &fanOutCaller{timeout: time.Millisecond * 100, slots: make(chan struct{}, 1)}
+		failed := errors.New("connection refused")
+
+		start := time.Now()
+		attempts, err := caller.call(context.Background(), func(context.Context) (io.ReadCloser, error) {
+			return nil, failed
+		}, "host-a", 5, func(io.Reader) error { return nil })
+
+		Expect(err).To(MatchError(failed))
+		Expect(len(attempts)).To(BeNumerically("<", 6))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+})
@@ -0,0 +1,226 @@
+package tsymbiotewebui
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"tailscale.com/ipn/ipnstate"
+)
+
+// RouteOverlap is a pair of hosts advertising overlapping subnet routes.
+// Identical prefixes are usually HA subnet routers, different ones are likely a mistake.
+type RouteOverlap struct {
+	Host        string `json:"host"`
+	Prefix      string `json:"prefix"`
+	OtherHost   string `json:"otherHost"`
+	OtherPrefix string `json:"otherPrefix"`
+	Identical   bool   `json:"identical"`
+}
+
+// RouteIssue is a single advertised route that isn't being used.
+type RouteIssue struct {
+	Host   string `json:"host"`
+	Prefix string `json:"prefix"`
+	// PrimaryHosts are the hosts other hosts see as primary for the prefix.
+	PrimaryHosts []string `json:"primaryHosts,omitempty"`
+}
+
+// ExitNodeIssue is a host configured to use an exit node it can't use.
+type ExitNodeIssue struct {
+	Host     string `json:"host"`
+	ExitNode string `json:"exitNode"`
+	Reason   string `json:"reason"`
+}
+
+// MissingRoutes is a host with accept-routes off while peers serve subnet routes it won't get.
+type MissingRoutes struct {
+	Host   string   `json:"host"`
+	Routes []string `json:"routes"`
+}
+
+// RouteConflictsReport flags subnet route and exit node problems across the fleet.
+type RouteConflictsReport struct {
+	Overlaps []RouteOverlap `json:"overlaps"`
+	// Unapproved routes are advertised but missing from the host's AllowedIPs.
+	Unapproved []RouteIssue `json:"unapproved"`
+	// NotPrimary routes are approved but another host is serving them.
+	NotPrimary    []RouteIssue      `json:"notPrimary"`
+	ExitNodes     []ExitNodeIssue   `json:"exitNodes"`
+	MissingRoutes []MissingRoutes   `json:"missingRoutes"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+// RouteConflicts collects Status and Prefs from every adapter and looks for route problems.
+// ?selector= limits the hosts analyzed, peers outside the selection are still used for primary routes.
+func (t *TSymbioteUIServer) RouteConflicts(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	fleet, err := t.collectFleet(r.Context(), r, r.URL.Query().Get("selector"), paths.Prefs)
+	if err != nil {
+		r.Log.Errorw("failed to collect fleet", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	t.WriteJson(w, r, analyzeRoutes(fleet))
+}
+
+type hostRoutes struct {
+	host       string
+	status     *ipnstate.Status
+	prefs      *fleetPrefs
+	advertised []netip.Prefix
+}
+
+func analyzeRoutes(fleet []*fleetHost) *RouteConflictsReport {
+	report := &RouteConflictsReport{
+		Overlaps:      []RouteOverlap{},
+		Unapproved:    []RouteIssue{},
+		NotPrimary:    []RouteIssue{},
+		ExitNodes:     []ExitNodeIssue{},
+		MissingRoutes: []MissingRoutes{},
+		Errors:        map[string]string{},
+	}
+
+	var hosts []hostRoutes
+	for _, host := range fleet {
+		prefs := &fleetPrefs{}
+		err := host.Decode(paths.Prefs, prefs)
+		if host.Status == nil || err != nil {
+			report.Errors[host.Host] = host.Error
+			if host.Error == "" {
+				report.Errors[host.Host] = err.Error()
+			}
+			continue
+		}
+
+		routes := hostRoutes{host: host.Host, status: host.Status, prefs: prefs}
+		for _, prefix := range prefs.AdvertiseRoutes {
+			// Exit node routes always overlap, they're covered by the exit node checks.
+			if prefix.Bits() != 0 {
+				routes.advertised = append(routes.advertised, prefix.Masked())
+			}
+		}
+		hosts = append(hosts, routes)
+	}
+
+	primaries := primaryHosts(fleet)
+
+	for i, host := range hosts {
+		for _, other := range hosts[i+1:] {
+			for _, prefix := range host.advertised {
+				for _, otherPrefix := range other.advertised {
+					if !prefix.Overlaps(otherPrefix) {
+						continue
+					}
+					report.Overlaps = append(report.Overlaps, RouteOverlap{
+						Host:        host.host,
+						Prefix:      prefix.String(),
+						OtherHost:   other.host,
+						OtherPrefix: otherPrefix.String(),
+						Identical:   prefix == otherPrefix,
+					})
+				}
+			}
+		}
+
+		self := host.status.Self
+		for _, prefix := range host.advertised {
+			issue := RouteIssue{Host: host.host, Prefix: prefix.String()}
+			switch {
+			case self.AllowedIPs == nil || !slices.Contains(self.AllowedIPs.AsSlice(), prefix):
+				report.Unapproved = append(report.Unapproved, issue)
+			case self.PrimaryRoutes == nil || !slices.Contains(self.PrimaryRoutes.AsSlice(), prefix):
+				issue.PrimaryHosts = primaries[prefix]
+				report.NotPrimary = append(report.NotPrimary, issue)
+			}
+		}
+
+		if issue, ok := exitNodeIssue(host); ok {
+			report.ExitNodes = append(report.ExitNodes, issue)
+		}
+
+		if missing := missingRoutes(host); len(missing.Routes) > 0 {
+			report.MissingRoutes = append(report.MissingRoutes, missing)
+		}
+	}
+
+	return report
+}
+
+// primaryHosts maps each subnet route to the hosts any adapter sees as primary for it.
+func primaryHosts(fleet []*fleetHost) map[netip.Prefix][]string {
+	primaries := map[netip.Prefix][]string{}
+	add := func(peer *ipnstate.PeerStatus) {
+		if peer == nil || peer.PrimaryRoutes == nil {
+			return
+		}
+		for _, prefix := range peer.PrimaryRoutes.All() {
+			if !slices.Contains(primaries[prefix], peer.HostName) {
+				primaries[prefix] = append(primaries[prefix], peer.HostName)
+			}
+		}
+	}
+
+	for _, host := range fleet {
+		if host.Status == nil {
+			continue
+		}
+		add(host.Status.Self)
+		for _, peer := range host.Status.Peer {
+			add(peer)
+		}
+	}
+
+	for _, hosts := range primaries {
+		slices.Sort(hosts)
+	}
+	return primaries
+}
+
+func exitNodeIssue(host hostRoutes) (ExitNodeIssue, bool) {
+	if host.prefs.ExitNodeID == "" {
+		return ExitNodeIssue{}, false
+	}
+
+	issue := ExitNodeIssue{
+		Host:     host.host,
+		ExitNode: exitNodeName(host.status, host.prefs.ExitNodeID),
+	}
+
+	exitNode := host.status.ExitNodeStatus
+	switch {
+	case exitNode == nil:
+		issue.Reason = "exit node is set but not in use"
+	case !exitNode.Online:
+		issue.Reason = "exit node is offline"
+	default:
+		return ExitNodeIssue{}, false
+	}
+	return issue, true
+}
+
+// missingRoutes lists the subnet routes peers are serving that a host with RouteAll off won't use.
+func missingRoutes(host hostRoutes) MissingRoutes {
+	missing := MissingRoutes{Host: host.host, Routes: []string{}}
+	if host.prefs.RouteAll {
+		return missing
+	}
+
+	for _, peer := range host.status.Peer {
+		if peer.PrimaryRoutes == nil {
+			continue
+		}
+		for _, prefix := range peer.PrimaryRoutes.All() {
+			// Exit node routes are opted into separately.
+			if prefix.Bits() == 0 || slices.Contains(missing.Routes, prefix.String()) {
+				continue
+			}
+			missing.Routes = append(missing.Routes, prefix.String())
+		}
+	}
+
+	slices.Sort(missing.Routes)
+	return missing
+}
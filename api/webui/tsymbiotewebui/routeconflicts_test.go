@@ -0,0 +1,69 @@
+package tsymbiotewebui
+
+import (
+	"net/netip"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/types/key"
+)
+
+var _ = Describe("analyzeRoutes", func() {
+	routes := func(advertise ...string) map[paths.KnownPath]any {
+		prefs := fleetPrefs{RouteAll: true}
+		for _, prefix := range advertise {
+			prefs.AdvertiseRoutes = append(prefs.AdvertiseRoutes, netip.MustParsePrefix(prefix))
+		}
+		return map[paths.KnownPath]any{paths.Prefs: prefs}
+	}
+
+	It("Should find overlapping, unapproved and non primary routes", func() {
+		fleet := []*fleetHost{
+			newTestFleetHost("r1", &ipnstate.Status{Self: &ipnstate.PeerStatus{
+				HostName:      "r1",
+				AllowedIPs:    prefixes("10.0.0.0/24"),
+				PrimaryRoutes: prefixes("10.0.0.0/24"),
+			}}, routes("10.0.0.0/24")),
+			newTestFleetHost("r2", &ipnstate.Status{Self: &ipnstate.PeerStatus{
+				HostName:   "r2",
+				AllowedIPs: prefixes("10.0.0.0/24"),
+			}}, routes("10.0.0.0/24")),
+			newTestFleetHost("r3", &ipnstate.Status{Self: &ipnstate.PeerStatus{HostName: "r3"}}, routes("10.0.0.0/16", "0.0.0.0/0")),
+		}
+
+		report := analyzeRoutes(fleet)
+
+		Expect(report.Overlaps).To(Equal([]RouteOverlap{
+			{Host: "r1", Prefix: "10.0.0.0/24", OtherHost: "r2", OtherPrefix: "10.0.0.0/24", Identical: true},
+			{Host: "r1", Prefix: "10.0.0.0/24", OtherHost: "r3", OtherPrefix: "10.0.0.0/16"},
+			{Host: "r2", Prefix: "10.0.0.0/24", OtherHost: "r3", OtherPrefix: "10.0.0.0/16"},
+		}))
+		Expect(report.Unapproved).To(Equal([]RouteIssue{{Host: "r3", Prefix: "10.0.0.0/16"}}))
+		Expect(report.NotPrimary).To(Equal([]RouteIssue{{Host: "r2", Prefix: "10.0.0.0/24", PrimaryHosts: []string{"r1"}}}))
+		Expect(report.ExitNodes).To(BeEmpty())
+		Expect(report.MissingRoutes).To(BeEmpty())
+		Expect(report.Errors).To(BeEmpty())
+	})
+
+	It("Should flag unusable exit nodes, skipped subnet routes and failed hosts", func() {
+		noRoutes := map[paths.KnownPath]any{paths.Prefs: fleetPrefs{ExitNodeID: "exit-id"}}
+		fleet := []*fleetHost{
+			newTestFleetHost("client", &ipnstate.Status{
+				Self: &ipnstate.PeerStatus{HostName: "client"},
+				Peer: map[key.NodePublic]*ipnstate.PeerStatus{
+					key.NewNode().Public(): {HostName: "router", PrimaryRoutes: prefixes("10.0.0.0/24", "0.0.0.0/0")},
+				},
+			}, noRoutes),
+			newTestFleetHost("down", nil, nil),
+		}
+		fleet[1].Error = "adapter unreachable"
+
+		report := analyzeRoutes(fleet)
+
+		Expect(report.ExitNodes).To(Equal([]ExitNodeIssue{{Host: "client", ExitNode: "exit-id", Reason: "exit node is set but not in use"}}))
+		Expect(report.MissingRoutes).To(Equal([]MissingRoutes{{Host: "client", Routes: []string{"10.0.0.0/24"}}}))
+		Expect(report.Errors).To(Equal(map[string]string{"down": "adapter unreachable"}))
+	})
+})
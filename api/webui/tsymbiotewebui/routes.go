@@ -6,6 +6,7 @@ import (
 
 	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
 	webuiembed "github.com/dhouti/tsymbiote/web-ui"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func (t *TSymbioteUIServer) RegisterRoutes() {
@@ -33,12 +34,43 @@ func (t *TSymbioteUIServer) RegisterRoutes() {
 	t.Route().Get().RegisterSimple("/debug/pprof/symbol", pprof.Symbol)
 	t.Route().Get().RegisterSimple("/debug/pprof/trace", pprof.Trace)
 
+	// Prometheus metrics, IE: the ping prober gauges. Scrapers are let in by tag, see metricsAuth.
+	t.metricsRoute().Get().RegisterSimple("/metrics", promhttp.HandlerFor(t.metrics, promhttp.HandlerOpts{}).ServeHTTP)
+
 	t.Route().Get().Register(paths.PeerMap.WebUI(), t.PeerMap)
+	t.Route().Get().Register(paths.PeerMap.WebUI()+"/snapshots", t.PeerMapSnapshots)
+	t.Route().Get().Register(paths.PeerMap.WebUI()+"/snapshot", t.PeerMapSnapshot)
+	t.Route().Get().Register(paths.PeerMap.WebUI()+"/changes", t.PeerMapChanges)
+	t.Route().Get().Register(paths.Hosts.WebUI(), t.Hosts)
+	t.Route().Get().Register(paths.Recordings.WebUI(), t.Recordings)
+	t.Route().Get().Register(paths.Recordings.WebUI()+"/{id}", t.DownloadRecording)
+	t.Route().Get().Register(paths.Jobs.WebUI(), t.Jobs)
+	t.Route().Get().Register(paths.Jobs.WebUI()+"/{id}", t.GetJob)
+	t.Route().Get().Register(paths.Runbooks.WebUI(), t.Runbooks)
+	t.Route().Get().Register(paths.Runbooks.WebUI()+"/{name}", t.GetRunbook)
+	t.Route().Get().Register(paths.Checks.WebUI(), t.Checks)
+	t.Route().Get().Register(paths.Inventory.WebUI(), t.Inventory)
+	t.Route().Get().Register(paths.RouteConflicts.WebUI(), t.RouteConflicts)
+	t.Route().Get().Register(paths.TailnetLock.WebUI(), t.TailnetLock)
+	t.Route().Get().Register(paths.ServeFleet.WebUI(), t.ServeFleet)
 
 	t.Route().Post().Register(paths.Ping.WebUI(), t.Ping)
 	t.Route().Post().Register(paths.QueryDNS.WebUI(), t.QueryDNS)
 	t.Route().Post().Register(paths.Pprof.WebUI(), t.Pprof)
 	t.Route().Post().Register(paths.Goroutines.WebUI(), t.Goroutines)
+	t.Route().Post().Register(paths.Recordings.WebUI()+"/upload", t.UploadRecording)
+	t.Route().Post().Register(paths.Jobs.WebUI()+"/submit", t.SubmitJob)
+	t.Route().Post().Register(paths.Jobs.WebUI()+"/{id}/cancel", t.CancelJob)
+	t.Route().Post().Register(paths.Assertions.WebUI(), t.Assertions)
+	t.Route().Post().Register(paths.Bundle.WebUI(), t.Bundle)
+	t.Route().Post().Register(paths.BugReport.WebUI(), t.BugReport)
+	t.Route().Post().Register(paths.DERPRegions.WebUI(), t.DERPRegions)
+	t.Route().Post().Register(paths.NAT.WebUI(), t.NAT)
+	t.Route().Post().Register(paths.PacketFilter.WebUI()+"/check", t.CheckPacketFilter)
+	t.Route().Post().Register(paths.Runbooks.WebUI()+"/upload", t.SaveRunbook)
+	t.Route().Post().Register(paths.Runbooks.WebUI()+"/{name}/run", t.RunRunbook)
+	t.Route().Post().Register(paths.Runbooks.WebUI()+"/{name}/delete", t.DeleteRunbook)
+	t.Route().Post().Register(paths.Checks.WebUI()+"/{name}/run", t.RunCheck)
 
 	t.Route().Post().Register(paths.Status.WebUI(), t.RelativeJSON)
 	t.Route().Post().Register(paths.Prefs.WebUI(), t.RelativeJSON)
@@ -46,7 +78,14 @@ func (t *TSymbioteUIServer) RegisterRoutes() {
 	t.Route().Post().Register(paths.DNSConfig.WebUI(), t.RelativeJSON)
 	t.Route().Post().Register(paths.ServeConfig.WebUI(), t.RelativeJSON)
 	t.Route().Post().Register(paths.AppConnRoutes.WebUI(), t.RelativeJSON)
+	t.Route().Post().Register(paths.DERPMap.WebUI(), t.RelativeJSON)
+	t.Route().Post().Register(paths.PacketFilter.WebUI(), t.RelativeJSON)
 
 	t.Route().Websocket().Register(paths.Logs.WebUI(), t.RelativeWebsocket)
 	t.Route().Websocket().Register(paths.BusEvents.WebUI(), t.RelativeWebsocket)
+	t.Route().Websocket().Register(paths.Replay.WebUI(), t.Replay)
+	t.Route().Websocket().Register(paths.Stream.WebUI(), t.Stream)
+
+	// Adapters in reverse connect mode dial in here, the WebUI then calls them over the tunnel.
+	t.tunnelRoute().Websocket().Register(paths.Tunnel.WebUI(), t.Tunnel)
 }
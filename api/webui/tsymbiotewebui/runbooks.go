@@ -0,0 +1,655 @@
+package tsymbiotewebui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"github.com/dhouti/tsymbiote/api/webui/expectation"
+	"sigs.k8s.io/yaml"
+)
+
+const runbooksPath = "/tmp/TSymbiote/runbooks/"
+
+// Streaming steps capture for this long unless the step sets a duration.
+const (
+	defaultRunbookCapture = time.Second * 10
+	maxRunbookCapture     = time.Minute * 5
+	// maxRunbookMessages is how many streamed messages are kept per host.
+	maxRunbookMessages = 1000
+)
+
+var (
+	runbookNameRegex  = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+	runbookParamRegex = regexp.MustCompile(`\$\{([a-zA-Z0-9_-]+)\}`)
+)
+
+// Runbook is a saved, ordered list of diagnostic steps stored as YAML.
+//
+//	name: cant-reach
+//	params:
+//	  - name: target
+//	    required: true
+//	steps:
+//	  - kind: Status
+//	  - kind: QueryDNS
+//	    params: {name: "${target}", queryType: A}
+//	  - kind: Ping
+//	    params: {targets: ["${target}"], count: 3, delay: 1s, pingType: disco}
+//	  - kind: Logs
+//	    duration: 30s
+type Runbook struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Params can be referenced anywhere in a step as ${name}.
+	Params []RunbookParam `json:"params,omitempty"`
+	Steps  []RunbookStep  `json:"steps"`
+}
+
+type RunbookParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// RunbookStep runs one WebUI endpoint, hosts default to the ones given when the runbook is run.
+type RunbookStep struct {
+	Name     string   `json:"name,omitempty"`
+	Kind     string   `json:"kind"`
+	Hosts    []string `json:"hosts,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	// Params are the rest of the endpoint input, IE: name and queryType for QueryDNS.
+	// Streaming kinds send them to the adapter as query params.
+	Params map[string]any `json:"params,omitempty"`
+	// Duration is how long streaming kinds like Logs are captured for.
+	Duration string `json:"duration,omitempty"`
+	// Expect is a CEL expression checked against every host result, see expectation.Expectation.
+	Expect string `json:"expect,omitempty"`
+}
+
+// RunbookRunInput is the body used to run a runbook.
+type RunbookRunInput struct {
+	Params   map[string]string `json:"params,omitempty"`
+	Hosts    []string          `json:"hosts,omitempty"`
+	Selector string            `json:"selector,omitempty"`
+}
+
+// RunbookReport combines the output of every step of a run.
+type RunbookReport struct {
+	Runbook  string              `json:"runbook"`
+	Params   map[string]string   `json:"params,omitempty"`
+	User     string              `json:"user,omitempty"`
+	TraceID  string              `json:"traceId"`
+	Started  time.Time           `json:"started"`
+	Finished time.Time           `json:"finished"`
+	Steps    []RunbookStepReport `json:"steps"`
+}
+
+type RunbookStepReport struct {
+	Name    string          `json:"name"`
+	Kind    string          `json:"kind"`
+	Hosts   []string        `json:"hosts"`
+	Started time.Time       `json:"started"`
+	Elapsed time.Duration   `json:"elapsed"`
+	Error   string          `json:"error,omitempty"`
+	Results json.RawMessage `json:"results,omitempty"`
+	// Expectation results are only set when the step has an expect.
+	Expect       string                    `json:"expect,omitempty"`
+	Passed       int                       `json:"passed,omitempty"`
+	Failed       int                       `json:"failed,omitempty"`
+	Expectations []types.ExpectationResult `json:"expectations,omitempty"`
+}
+
+// runbookStreamResult is the per host output of a streaming step.
+type runbookStreamResult struct {
+	Host     string   `json:"host"`
+	Error    string   `json:"error,omitempty"`
+	Messages []string `json:"messages"`
+	// Truncated is set when more than maxRunbookMessages were received.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// runbookStreamKinds are captured from the adapter websocket instead of calling a fan-out handler.
+var runbookStreamKinds = []paths.KnownPath{paths.Logs, paths.BusEvents}
+
+func runbookFile(name string) string {
+	return filepath.Join(runbooksPath, fmt.Sprintf("%s.yaml", name))
+}
+
+// parseRunbook decodes and validates a runbook, every step kind and param reference is checked.
+func (t *TSymbioteUIServer) parseRunbook(data []byte) (*Runbook, error) {
+	runbook := &Runbook{}
+	err := yaml.UnmarshalStrict(data, runbook)
+	if err != nil {
+		return nil, err
+	}
+
+	if !runbookNameRegex.MatchString(runbook.Name) {
+		return nil, fmt.Errorf("invalid runbook name: %q", runbook.Name)
+	}
+
+	if len(runbook.Steps) == 0 {
+		return nil, errors.New("runbook has no steps")
+	}
+
+	declared := map[string]bool{}
+	for _, param := range runbook.Params {
+		if !runbookNameRegex.MatchString(param.Name) {
+			return nil, fmt.Errorf("invalid param name: %q", param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	for i, step := range runbook.Steps {
+		err := t.validateRunbookStep(step)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+
+		raw, err := json.Marshal(step)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range runbookParamRegex.FindAllStringSubmatch(string(raw), -1) {
+			if !declared[match[1]] {
+				return nil, fmt.Errorf("step %d: undeclared param: %q", i+1, match[1])
+			}
+		}
+	}
+
+	return runbook, nil
+}
+
+// validateRunbookStep checks everything that doesn't depend on params, values with ${ are checked once substituted.
+func (t *TSymbioteUIServer) validateRunbookStep(step RunbookStep) error {
+	kind := t.runbookKind(step.Kind)
+	_, handled := t.jobHandlers()[kind]
+	if !handled && !slices.Contains(runbookStreamKinds, kind) {
+		return fmt.Errorf("unsupported kind: %q", step.Kind)
+	}
+
+	if step.Duration != "" && !strings.Contains(step.Duration, "${") {
+		_, err := time.ParseDuration(step.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+	}
+
+	if step.Expect != "" && !strings.Contains(step.Expect, "${") {
+		_, err := expectation.NewExpectation(step.Expect)
+		if err != nil {
+			return fmt.Errorf("invalid expect: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runbookKind looks up a step kind by name, End is returned for unknown kinds.
+func (t *TSymbioteUIServer) runbookKind(kind string) paths.KnownPath {
+	for _, path := range paths.Paths() {
+		if path.String() == kind {
+			return path
+		}
+	}
+	return paths.End
+}
+
+func (t *TSymbioteUIServer) loadRunbook(name string) (*Runbook, []byte, error) {
+	if !runbookNameRegex.MatchString(name) {
+		return nil, nil, errors.New("invalid runbook name")
+	}
+
+	data, err := os.ReadFile(runbookFile(name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runbook, err := t.parseRunbook(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return runbook, data, nil
+}
+
+// Runbooks lists the stored runbooks.
+func (t *TSymbioteUIServer) Runbooks(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	entries, err := os.ReadDir(runbooksPath)
+	if err != nil && !os.IsNotExist(err) {
+		r.Log.Errorw("failed to read runbooks directory", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	runbooks := []*Runbook{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		runbook, _, err := t.loadRunbook(name)
+		if err != nil {
+			r.Log.Errorw("failed to load runbook", "runbook", name, "error", err)
+			continue
+		}
+		runbooks = append(runbooks, runbook)
+	}
+
+	t.WriteJson(w, r, runbooks)
+}
+
+// GetRunbook returns the runbook YAML as it was saved.
+func (t *TSymbioteUIServer) GetRunbook(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	name := r.PathValue("name")
+
+	_, data, err := t.loadRunbook(name)
+	if err != nil {
+		r.Log.Errorw("failed to load runbook", "runbook", name, "error", err)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	_, err = w.Write(data)
+	if err != nil {
+		r.Log.Errorw("failed to write runbook", "error", err)
+	}
+}
+
+// SaveRunbook validates a YAML runbook and stores it under its name, replacing any runbook with the same name.
+// Runbooks are capped at MaxRunbookSize.
+func (t *TSymbioteUIServer) SaveRunbook(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, consts.MaxRunbookSize))
+	if err != nil {
+		r.Log.Errorw("failed to read runbook", "error", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			r.SetStatusCode(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	runbook, err := t.parseRunbook(data)
+	if err != nil {
+		r.Log.Errorw("invalid runbook", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	err = os.MkdirAll(runbooksPath, 0770)
+	if err != nil {
+		r.Log.Errorw("failed to create runbooks directory", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	err = os.WriteFile(runbookFile(runbook.Name), data, 0660)
+	if err != nil {
+		r.Log.Errorw("failed to save runbook", "error", err)
+		r.SetStatusCode(w, http.StatusInternalServerError)
+		return
+	}
+
+	r.Log.Infow("runbook saved", "runbook", runbook.Name)
+	t.WriteJson(w, r, runbook)
+}
+
+// DeleteRunbook removes a stored runbook.
+func (t *TSymbioteUIServer) DeleteRunbook(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	name := r.PathValue("name")
+	if !runbookNameRegex.MatchString(name) {
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	err := os.Remove(runbookFile(name))
+	if err != nil {
+		r.Log.Errorw("failed to delete runbook", "runbook", name, "error", err)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	r.Log.Infow("runbook deleted", "runbook", name)
+	r.SetStatusCode(w, http.StatusOK)
+}
+
+// RunRunbook runs every step in order and returns one combined report.
+// A failed step is recorded in the report and the remaining steps still run.
+func (t *TSymbioteUIServer) RunRunbook(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	name := r.PathValue("name")
+
+	runbook, _, err := t.loadRunbook(name)
+	if err != nil {
+		r.Log.Errorw("failed to load runbook", "runbook", name, "error", err)
+		r.SetStatusCode(w, http.StatusNotFound)
+		return
+	}
+
+	input := &RunbookRunInput{}
+	err = json.NewDecoder(r.Body).Decode(input)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.Log.Errorw("failed to decode runbook input", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	params, err := runbookParams(runbook, input.Params)
+	if err != nil {
+		r.Log.Errorw("invalid runbook params", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	report := &RunbookReport{
+		Runbook: runbook.Name,
+		Params:  params,
+		User:    r.UserName,
+		TraceID: r.TraceID,
+		Started: time.Now(),
+		Steps:   []RunbookStepReport{},
+	}
+
+	r.Log.Infow("running runbook", "runbook", runbook.Name, "steps", len(runbook.Steps))
+
+	// Share the inventory across steps so selectors are only resolved against adapters once.
+	resolver := t.newHostResolver(r)
+	for i, step := range runbook.Steps {
+		if r.Context().Err() != nil {
+			break
+		}
+
+		step = substituteRunbookParams(step, params)
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("%d. %s", i+1, step.Kind)
+		}
+
+		stepReport := t.runRunbookStep(r, resolver, step, input)
+		if stepReport.Error != "" {
+			r.Log.Infow("runbook step failed", "runbook", runbook.Name, "step", stepReport.Name, "error", stepReport.Error)
+		}
+		report.Steps = append(report.Steps, stepReport)
+	}
+
+	report.Finished = time.Now()
+	t.WriteJson(w, r, report)
+}
+
+// runbookParams fills in defaults and makes sure every required param is set.
+func runbookParams(runbook *Runbook, given map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, param := range runbook.Params {
+		value, ok := given[param.Name]
+		if !ok || value == "" {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return nil, fmt.Errorf("missing required param: %s", param.Name)
+		}
+		params[param.Name] = value
+	}
+
+	for name := range given {
+		if _, ok := params[name]; !ok {
+			return nil, fmt.Errorf("unknown param: %s", name)
+		}
+	}
+	return params, nil
+}
+
+// substituteRunbookParams replaces ${name} in every string of the step.
+func substituteRunbookParams(step RunbookStep, params map[string]string) RunbookStep {
+	replace := func(value string) string {
+		return runbookParamRegex.ReplaceAllStringFunc(value, func(match string) string {
+			return params[runbookParamRegex.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	var walk func(value any) any
+	walk = func(value any) any {
+		switch typed := value.(type) {
+		case string:
+			return replace(typed)
+		case []any:
+			out := make([]any, 0, len(typed))
+			for _, item := range typed {
+				out = append(out, walk(item))
+			}
+			return out
+		case map[string]any:
+			out := make(map[string]any, len(typed))
+			for key, item := range typed {
+				out[key] = walk(item)
+			}
+			return out
+		}
+		return value
+	}
+
+	step.Selector = replace(step.Selector)
+	step.Duration = replace(step.Duration)
+	step.Expect = replace(step.Expect)
+	hosts := make([]string, 0, len(step.Hosts))
+	for _, host := range step.Hosts {
+		hosts = append(hosts, replace(host))
+	}
+	step.Hosts = hosts
+	if step.Params != nil {
+		step.Params = walk(step.Params).(map[string]any)
+	}
+	return step
+}
+
+func (t *TSymbioteUIServer) runRunbookStep(r *tsymbiote.HTTPRequest, resolver *hostResolver, step RunbookStep, input *RunbookRunInput) RunbookStepReport {
+	report := RunbookStepReport{
+		Name:    step.Name,
+		Kind:    step.Kind,
+		Started: time.Now(),
+	}
+
+	fail := func(err error) RunbookStepReport {
+		report.Error = err.Error()
+		report.Elapsed = time.Since(report.Started)
+		return report
+	}
+
+	hosts, selector := step.Hosts, step.Selector
+	if len(hosts) == 0 && selector == "" {
+		hosts, selector = input.Hosts, input.Selector
+	}
+
+	hosts, err := resolver.Resolve(r.Context(), hosts, selector)
+	if err != nil {
+		return fail(err)
+	}
+	report.Hosts = hosts
+
+	if len(hosts) == 0 {
+		return fail(errors.New("step has no hosts"))
+	}
+
+	kind := t.runbookKind(step.Kind)
+	if slices.Contains(runbookStreamKinds, kind) {
+		results, err := t.captureRunbookStream(r, kind, hosts, step)
+		if err != nil {
+			return fail(err)
+		}
+		report.Results = results
+		return checkRunbookStep(r.Context(), report, step)
+	}
+
+	body := maps.Clone(step.Params)
+	if body == nil {
+		body = map[string]any{}
+	}
+
+	if kind == paths.Ping {
+		// Ping takes its targets per source host.
+		args := []pingTargets{}
+		targets := []string{}
+		rawTargets, err := json.Marshal(body["targets"])
+		if err != nil {
+			return fail(fmt.Errorf("invalid targets: %w", err))
+		}
+		err = json.Unmarshal(rawTargets, &targets)
+		if err != nil {
+			return fail(fmt.Errorf("targets must be a list of hosts: %w", err))
+		}
+		// A missing targets param decodes to nothing, which would ping nothing and pass.
+		if len(targets) == 0 {
+			return fail(errors.New("ping steps need at least one target"))
+		}
+		for _, host := range hosts {
+			args = append(args, pingTargets{Host: host, Targets: targets})
+		}
+		delete(body, "targets")
+		body["args"] = args
+	} else {
+		body["hosts"] = hosts
+	}
+
+	rawBody, err := json.Marshal(body)
+	if err != nil {
+		return fail(err)
+	}
+
+	request, err := http.NewRequestWithContext(r.Context(), http.MethodPost, kind.WebUI(), io.NopCloser(bytes.NewReader(rawBody)))
+	if err != nil {
+		return fail(err)
+	}
+
+	writer := &bufferedResponseWriter{header: http.Header{}}
+	t.jobHandlers()[kind](writer, &tsymbiote.HTTPRequest{
+		Request:  request,
+		Log:      r.Log,
+		TraceID:  r.TraceID,
+		UserName: r.UserName,
+	})
+
+	if writer.status >= http.StatusBadRequest {
+		return fail(fmt.Errorf("step returned %d %s", writer.status, http.StatusText(writer.status)))
+	}
+
+	report.Results = json.RawMessage(bytes.TrimSpace(writer.body.Bytes()))
+	return checkRunbookStep(r.Context(), report, step)
+}
+
+// checkRunbookStep evaluates the step expect against the results and finishes the report.
+func checkRunbookStep(ctx context.Context, report RunbookStepReport, step RunbookStep) RunbookStepReport {
+	if step.Expect == "" {
+		report.Elapsed = time.Since(report.Started)
+		return report
+	}
+	report.Expect = step.Expect
+
+	expect, err := expectation.NewExpectation(step.Expect)
+	if err == nil {
+		report.Expectations, err = expect.EvaluateAll(ctx, report.Results)
+	}
+	if err != nil {
+		report.Error = err.Error()
+		report.Elapsed = time.Since(report.Started)
+		return report
+	}
+
+	for _, result := range report.Expectations {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+
+	report.Elapsed = time.Since(report.Started)
+	return report
+}
+
+// captureRunbookStream records an adapter websocket from every host for the step duration.
+func (t *TSymbioteUIServer) captureRunbookStream(r *tsymbiote.HTTPRequest, kind paths.KnownPath, hosts []string, step RunbookStep) (json.RawMessage, error) {
+	duration := defaultRunbookCapture
+	if step.Duration != "" {
+		parsed, err := time.ParseDuration(step.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		duration = min(parsed, maxRunbookCapture)
+	}
+
+	params := url.Values{}
+	for param, value := range step.Params {
+		params.Set(param, fmt.Sprint(value))
+	}
+
+	captureCtx, captureCancel := context.WithTimeout(r.Context(), duration)
+	defer captureCancel()
+
+	results := make([]runbookStreamResult, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Go(func() {
+			result := runbookStreamResult{Host: host, Messages: []string{}}
+			defer func() { results[i] = result }()
+
+			conn, err := t.dialAdapterWebsocket(captureCtx, r, host, kind.Adapter(), params)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+
+			err = readAdapterWebsocket(captureCtx, r, conn, func(messageType int, message []byte) bool {
+				if len(result.Messages) >= maxRunbookMessages {
+					result.Truncated = true
+					return true
+				}
+				result.Messages = append(result.Messages, string(message))
+				return true
+			})
+			// Running out the capture window is how every stream ends.
+			if err != nil && captureCtx.Err() == nil {
+				result.Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	return json.Marshal(results)
+}
+
+// bufferedResponseWriter captures a handler response so it can be embedded in another response.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
+
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
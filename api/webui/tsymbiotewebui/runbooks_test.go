@@ -0,0 +1,23 @@
+package tsymbiotewebui
+
+import (
+	"net/http/httptest"
+
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+var _ = Describe("runRunbookStep", func() {
+	It("Should fail ping steps without targets instead of pinging nothing", func() {
+		t := &TSymbioteUIServer{}
+		r := &tsymbiote.HTTPRequest{Request: httptest.NewRequest("POST", "/api/Runbooks/check/run", nil), Log: zap.NewNop().Sugar()}
+
+		for _, params := range []map[string]any{nil, {"targets": nil}, {"targets": []any{}}} {
+			step := RunbookStep{Name: "ping", Kind: "Ping", Hosts: []string{"web-1"}, Params: params}
+			report := t.runRunbookStep(r, t.newHostResolver(r), step, &RunbookRunInput{})
+			Expect(report.Error).To(ContainSubstring("at least one target"), "%v", params)
+		}
+	})
+})
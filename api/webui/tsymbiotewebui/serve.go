@@ -0,0 +1,161 @@
+package tsymbiotewebui
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/ipn"
+)
+
+// ServeFleetReport lists every serve and funnel handler in the fleet with any problems found.
+type ServeFleetReport struct {
+	// Reference is the host every other host's config is compared to, when set.
+	Reference string           `json:"reference,omitempty"`
+	Hosts     []ServeFleetHost `json:"hosts"`
+}
+
+type ServeFleetHost struct {
+	Host     string              `json:"host"`
+	Error    string              `json:"error,omitempty"`
+	ETag     string              `json:"etag,omitempty"`
+	Handlers []ServeFleetHandler `json:"handlers"`
+	// Drift is set when a reference was given, handlers are compared by ServeHandler.Key.
+	Drift *ServeDrift `json:"drift,omitempty"`
+}
+
+type ServeFleetHandler struct {
+	types.ServeHandler
+	Probe    *types.ServeProbeResult `json:"probe,omitempty"`
+	Problems []string                `json:"problems,omitempty"`
+}
+
+// ServeDrift is how a host's handlers differ from the reference host's.
+type ServeDrift struct {
+	Missing []string `json:"missing"`
+	Extra   []string `json:"extra"`
+}
+
+// ServeFleet flattens the serve config of every host, probes proxy targets from the host itself and checks funnel access.
+// With ?reference=<host> every host is compared against that host's handlers.
+func (t *TSymbioteUIServer) ServeFleet(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	query := r.URL.Query()
+
+	fleet, err := t.collectFleet(r.Context(), r, query.Get("selector"), paths.ServeConfig, paths.ServeProbe)
+	if err != nil {
+		r.Log.Errorw("failed to collect fleet", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	report, err := buildServeFleet(fleet, query.Get("reference"))
+	if err != nil {
+		r.Log.Errorw("failed to build serve fleet", "error", err)
+		r.SetStatusCode(w, http.StatusBadRequest)
+		return
+	}
+
+	t.WriteJson(w, r, report)
+}
+
+func buildServeFleet(fleet []*fleetHost, reference string) (*ServeFleetReport, error) {
+	report := &ServeFleetReport{
+		Reference: reference,
+		Hosts:     []ServeFleetHost{},
+	}
+
+	var referenceKeys []string
+	for _, host := range fleet {
+		serveHost := ServeFleetHost{
+			Host:     host.Host,
+			Error:    host.Error,
+			Handlers: []ServeFleetHandler{},
+		}
+
+		config := &types.ServeConfig{}
+		if err := host.Decode(paths.ServeConfig, config); err != nil {
+			if serveHost.Error == "" {
+				serveHost.Error = err.Error()
+			}
+			report.Hosts = append(report.Hosts, serveHost)
+			if host.Host == reference {
+				return nil, fmt.Errorf("no serve config for reference host %s: %s", reference, serveHost.Error)
+			}
+			continue
+		}
+		serveHost.ETag = config.ETag
+
+		// Probes are best effort, a failed probe call leaves handlers unchecked.
+		var probes []types.ServeProbeResult
+		_ = host.Decode(paths.ServeProbe, &probes)
+
+		for _, handler := range types.FlattenServeConfig(&config.ServeConfig) {
+			fleetHandler := ServeFleetHandler{ServeHandler: handler}
+
+			probeIndex := slices.IndexFunc(probes, func(probe types.ServeProbeResult) bool { return probe.Target == handler.Target })
+			if probeIndex >= 0 && handler.ProbeAddress() != "" {
+				fleetHandler.Probe = &probes[probeIndex]
+				if !fleetHandler.Probe.Reachable && fleetHandler.Probe.Skipped == "" {
+					fleetHandler.Problems = append(fleetHandler.Problems, fmt.Sprintf("target %s does not answer: %s", handler.Target, fleetHandler.Probe.Error))
+				}
+			}
+
+			if handler.Funnel && host.Status != nil && host.Status.Self != nil {
+				if err := ipn.CheckFunnelAccess(handler.Port, host.Status.Self); err != nil {
+					fleetHandler.Problems = append(fleetHandler.Problems, err.Error())
+				}
+			}
+
+			serveHost.Handlers = append(serveHost.Handlers, fleetHandler)
+		}
+
+		if host.Host == reference {
+			referenceKeys = serveKeys(serveHost.Handlers)
+		}
+		report.Hosts = append(report.Hosts, serveHost)
+	}
+
+	if reference == "" {
+		return report, nil
+	}
+	if referenceKeys == nil {
+		return nil, fmt.Errorf("reference host %s not found", reference)
+	}
+
+	for i, host := range report.Hosts {
+		if host.Error != "" || host.Host == reference {
+			continue
+		}
+
+		keys := serveKeys(host.Handlers)
+		drift := &ServeDrift{Missing: []string{}, Extra: []string{}}
+		for _, key := range referenceKeys {
+			if !slices.Contains(keys, key) {
+				drift.Missing = append(drift.Missing, key)
+			}
+		}
+		for _, key := range keys {
+			if !slices.Contains(referenceKeys, key) {
+				drift.Extra = append(drift.Extra, key)
+			}
+		}
+		if len(drift.Missing) > 0 || len(drift.Extra) > 0 {
+			report.Hosts[i].Drift = drift
+		}
+	}
+	return report, nil
+}
+
+// serveKeys skips foreground handlers, they belong to an interactive session rather than the host's config.
+func serveKeys(handlers []ServeFleetHandler) []string {
+	keys := []string{}
+	for _, handler := range handlers {
+		if handler.Foreground == "" {
+			keys = append(keys, handler.Key())
+		}
+	}
+	return keys
+}
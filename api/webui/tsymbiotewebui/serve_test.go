@@ -0,0 +1,94 @@
+package tsymbiotewebui
+
+import (
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn"
+	"tailscale.com/ipn/ipnstate"
+)
+
+var _ = Describe("buildServeFleet", func() {
+	proxy := func(hostPort ipn.HostPort, funnel bool) ipn.ServeConfig {
+		return ipn.ServeConfig{
+			TCP: map[uint16]*ipn.TCPPortHandler{443: {HTTPS: true}},
+			Web: map[ipn.HostPort]*ipn.WebServerConfig{
+				hostPort: {Handlers: map[string]*ipn.HTTPHandler{"/": {Proxy: "http://127.0.0.1:8080"}}},
+			},
+			AllowFunnel: map[ipn.HostPort]bool{hostPort: funnel},
+		}
+	}
+
+	fleet := func() []*fleetHost {
+		withForward := proxy("s2.example.ts.net:443", false)
+		withForward.TCP[22] = &ipn.TCPPortHandler{TCPForward: "127.0.0.1:22"}
+
+		return []*fleetHost{
+			newTestFleetHost("s1", &ipnstate.Status{Self: &ipnstate.PeerStatus{HostName: "s1"}}, map[paths.KnownPath]any{
+				paths.ServeConfig: types.ServeConfig{ServeConfig: proxy("s1.example.ts.net:443", true), ETag: "etag-1"},
+				paths.ServeProbe:  []types.ServeProbeResult{{Target: "http://127.0.0.1:8080", Address: "127.0.0.1:8080", Error: "connection refused"}},
+			}),
+			newTestFleetHost("s2", &ipnstate.Status{Self: &ipnstate.PeerStatus{HostName: "s2"}}, map[paths.KnownPath]any{
+				paths.ServeConfig: types.ServeConfig{ServeConfig: withForward},
+			}),
+			newTestFleetHost("s3", nil, nil),
+		}
+	}
+
+	It("Should report unreachable targets and funnel problems", func() {
+		report, err := buildServeFleet(fleet(), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Hosts).To(HaveLen(3))
+
+		s1 := report.Hosts[0]
+		Expect(s1.ETag).To(Equal("etag-1"))
+		Expect(s1.Handlers).To(HaveLen(1))
+		Expect(s1.Handlers[0].Probe).NotTo(BeNil())
+		Expect(s1.Handlers[0].Problems).To(HaveLen(2))
+		Expect(s1.Handlers[0].Problems[0]).To(Equal("target http://127.0.0.1:8080 does not answer: connection refused"))
+		Expect(s1.Handlers[0].Problems[1]).To(ContainSubstring("HTTPS must be enabled"))
+
+		s2 := report.Hosts[1]
+		Expect(s2.Handlers).To(HaveLen(2))
+		Expect(s2.Handlers[0].Probe).To(BeNil())
+		Expect(s2.Handlers[0].Problems).To(BeEmpty())
+		Expect(s2.Drift).To(BeNil())
+
+		Expect(report.Hosts[2].Error).NotTo(BeEmpty())
+	})
+
+	It("Should not flag targets the adapter skipped", func() {
+		hosts := fleet()
+		hosts[0] = newTestFleetHost("s1", &ipnstate.Status{Self: &ipnstate.PeerStatus{HostName: "s1"}}, map[paths.KnownPath]any{
+			paths.ServeConfig: types.ServeConfig{ServeConfig: proxy("s1.example.ts.net:443", false)},
+			paths.ServeProbe:  []types.ServeProbeResult{{Target: "http://127.0.0.1:8080", Address: "127.0.0.1:8080", Skipped: "tailscaled is in another network namespace than the adapter"}},
+		})
+
+		report, err := buildServeFleet(hosts, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Hosts[0].Handlers[0].Probe).NotTo(BeNil())
+		Expect(report.Hosts[0].Handlers[0].Problems).To(BeEmpty())
+	})
+
+	It("Should compare every host against the reference", func() {
+		report, err := buildServeFleet(fleet(), "s1")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Hosts[0].Drift).To(BeNil())
+		Expect(report.Hosts[1].Drift).To(Equal(&ServeDrift{
+			Missing: []string{"web :443/ proxy=http://127.0.0.1:8080 funnel"},
+			Extra:   []string{"tcp :22 tcp-forward=127.0.0.1:22", "web :443/ proxy=http://127.0.0.1:8080"},
+		}))
+		// Hosts without a config can't drift.
+		Expect(report.Hosts[2].Drift).To(BeNil())
+	})
+
+	It("Should fail when the reference has no config or isn't in the fleet", func() {
+		_, err := buildServeFleet(fleet(), "s3")
+		Expect(err).To(HaveOccurred())
+
+		_, err = buildServeFleet(fleet(), "missing")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -4,12 +4,16 @@ import (
 	"context"
 	"net/http"
 	"slices"
+	"sync/atomic"
 
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
 	"github.com/dhouti/tsymbiote/api/webui/client"
 	"github.com/dhouti/tsymbiote/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 	"tailscale.com/client/tailscale/v2"
 )
 
@@ -19,6 +23,15 @@ type TSymbioteUIServer struct {
 	*utils.TSClient
 
 	allowedUsers []string
+	jobs         *jobManager
+	limits       requestLimits
+	scheduler    *scheduler
+	metrics      *prometheus.Registry
+
+	peerMapHistory *peerMapHistory
+
+	hostsRefresh     singleflight.Group
+	hostsRefreshedAt atomic.Int64
 }
 
 func NewTSymbioteUI() tsymbiote.TSymbiote {
@@ -57,12 +70,56 @@ func NewTSymbioteUI() tsymbiote.TSymbiote {
 		Client:          client,
 		TSClient:        oauth,
 		allowedUsers:    allowed,
+		metrics:         prometheus.NewRegistry(),
+		jobs:            newJobManager(viper.GetInt("job-adapter-concurrency")),
+		limits: requestLimits{
+			Timeout:     viper.GetDuration("max-request-timeout"),
+			Retries:     viper.GetInt("max-request-retries"),
+			Parallelism: viper.GetInt("max-request-parallelism"),
+		},
+	}
+
+	if scheduleFile := viper.GetString("schedule-file"); scheduleFile != "" {
+		webui.scheduler, err = webui.loadSchedule(scheduleFile)
+		if err != nil {
+			webui.Log.Errorw("failed to load schedule file", "file", scheduleFile, "error", err)
+			return nil
+		}
+		webui.RunWSFunc(webui.scheduler.run)
+	}
+
+	err = webui.startPeerMapHistory(viper.GetDuration("peermap-snapshot-interval"), viper.GetDuration("peermap-snapshot-retention"))
+	if err != nil {
+		webui.Log.Errorw("failed to start peer map snapshots", "error", err)
+		return nil
+	}
+
+	err = webui.startProber(webui.metrics)
+	if err != nil {
+		webui.Log.Errorw("failed to start ping prober", "error", err)
+		return nil
 	}
 
 	webui.RegisterRoutes()
 	return webui
 }
 
+// backgroundRequest builds a request for work the server starts on its own, IE: scheduled checks.
+func (t *TSymbioteUIServer) backgroundRequest(ctx context.Context, user string) (*tsymbiote.HTTPRequest, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	traceID := uuid.New().String()
+	return &tsymbiote.HTTPRequest{
+		Request:  request,
+		Log:      t.Log.With(zap.String("trace_id", traceID), zap.String("user", user)),
+		TraceID:  traceID,
+		UserName: user,
+	}, nil
+}
+
 func (t *TSymbioteUIServer) Route() *tsymbiote.MiddlewareChain {
 	middleware := &tsymbiote.MiddlewareChain{
 		TSymbiote: t,
@@ -78,6 +135,24 @@ func (t *TSymbioteUIServer) Route() *tsymbiote.MiddlewareChain {
 	return middleware
 }
 
+// tunnelRoute is used for connections opened by adapters rather than users.
+func (t *TSymbioteUIServer) tunnelRoute() *tsymbiote.MiddlewareChain {
+	middleware := t.RouteNoAuth()
+	if !viper.GetBool("dev") {
+		middleware.Add(t.tunnelAuth)
+	}
+	return middleware
+}
+
+// metricsRoute is for Prometheus scrapers, they have no user login so they're let in by tag instead.
+func (t *TSymbioteUIServer) metricsRoute() *tsymbiote.MiddlewareChain {
+	middleware := t.RouteNoAuth()
+	if !viper.GetBool("dev") {
+		middleware.Add(t.metricsAuth)
+	}
+	return middleware
+}
+
 // uiAuth uses the tailscale local client to ensure requests can only proceed if they came from an authorized user.
 // This uses a flag at startup `allowed-users`
 func (t *TSymbioteUIServer) uiAuth(next tsymbiote.HandlerFunc) tsymbiote.HandlerFunc {
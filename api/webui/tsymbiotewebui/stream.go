@@ -0,0 +1,464 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/gorilla/websocket"
+)
+
+// Actions accepted from the client on the Stream websocket.
+const (
+	StreamActionSubscribe   = "subscribe"
+	StreamActionUnsubscribe = "unsubscribe"
+	StreamActionPause       = "pause"
+	StreamActionResume      = "resume"
+)
+
+// Message types sent to the client on the Stream websocket.
+const (
+	StreamTypeMessage = "message"
+	StreamTypeStatus  = "status"
+)
+
+// Statuses sent to the client for a single host/kind stream.
+const (
+	StreamStatusConnecting   = "connecting"
+	StreamStatusSubscribed   = "subscribed"
+	StreamStatusUnsubscribed = "unsubscribed"
+	StreamStatusPaused       = "paused"
+	StreamStatusResumed      = "resumed"
+	StreamStatusDisconnected = "adapter disconnected"
+	StreamStatusReconnecting = "reconnecting"
+	StreamStatusRecording    = "recording"
+	StreamStatusError        = "error"
+)
+
+// StreamControl is sent by the client to manage subscriptions.
+// Pause and resume apply to every matching subscription, empty host or kind match all of them.
+type StreamControl struct {
+	Action string `json:"action"`
+	Host   string `json:"host,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	// Params are forwarded to the adapter as query params, IE: bus event filters.
+	Params map[string]string `json:"params,omitempty"`
+	// Record names a recording of the subscription, set on subscribe to start one.
+	Record string `json:"record,omitempty"`
+}
+
+// StreamMessage is sent to the client for both stream data and status updates.
+type StreamMessage struct {
+	Type    string `json:"type"`
+	Host    string `json:"host,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	Message []byte `json:"message,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Dropped is the count of messages discarded while a stream was paused, sent on resume.
+	Dropped int64 `json:"dropped,omitempty"`
+	// Attempt and RetryIn are set while reconnecting to an adapter.
+	Attempt int           `json:"attempt,omitempty"`
+	RetryIn time.Duration `json:"retryIn,omitempty"`
+	// Recording is the ID of the recording started for a subscription.
+	Recording string `json:"recording,omitempty"`
+}
+
+// streamKinds are the adapter websockets that can be subscribed to.
+var streamKinds = map[string]paths.KnownPath{
+	paths.Logs.String():      paths.Logs,
+	paths.BusEvents.String(): paths.BusEvents,
+}
+
+type streamKey struct {
+	Host string
+	Kind string
+}
+
+type streamSubscription struct {
+	cancel  context.CancelFunc
+	paused  atomic.Bool
+	dropped atomic.Int64
+}
+
+// streamSession holds every subscription for one client websocket.
+type streamSession struct {
+	*TSymbioteUIServer
+	r *tsymbiote.HTTPRequest
+	// ctx is canceled when the client websocket dies, subscriptions derive from it.
+	ctx context.Context
+	out chan tsymbiote.WebsocketMessage
+
+	mu   sync.Mutex
+	subs map[streamKey]*streamSubscription
+}
+
+// Stream is a single long lived websocket per client, adapters are added and removed with StreamControl messages.
+func (t *TSymbioteUIServer) Stream(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	// Used to signal kill all adapter sockets across routines
+	clientDeathCtx, deathFunc := context.WithCancel(context.Background())
+
+	session := &streamSession{
+		TSymbioteUIServer: t,
+		r:                 r,
+		ctx:               clientDeathCtx,
+		out:               make(chan tsymbiote.WebsocketMessage),
+		subs:              map[streamKey]*streamSubscription{},
+	}
+
+	t.RunWSFunc(session.clientReader(deathFunc))
+	t.RunWSFunc(session.clientWriter(deathFunc))
+}
+
+// send queues a message for the client writer, dropping it if the client is gone.
+func (s *streamSession) send(message StreamMessage) {
+	out, err := json.Marshal(message)
+	if err != nil {
+		s.r.Log.Errorw("failed to marshal stream message", "error", err)
+		return
+	}
+
+	select {
+	case s.out <- tsymbiote.WebsocketMessage{Type: websocket.TextMessage, Message: out}:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *streamSession) sendStatus(key streamKey, status string, err error) {
+	message := StreamMessage{
+		Type:   StreamTypeStatus,
+		Host:   key.Host,
+		Kind:   key.Kind,
+		Status: status,
+	}
+	if err != nil {
+		message.Error = err.Error()
+	}
+	s.send(message)
+}
+
+func (s *streamSession) handleControl(control StreamControl) {
+	key := streamKey{Host: control.Host, Kind: control.Kind}
+
+	switch control.Action {
+	case StreamActionSubscribe:
+		s.subscribe(key, control.Params, control.Record)
+	case StreamActionUnsubscribe:
+		s.unsubscribe(key)
+	case StreamActionPause:
+		s.setPaused(key, true)
+	case StreamActionResume:
+		s.setPaused(key, false)
+	default:
+		s.sendStatus(key, StreamStatusError, fmt.Errorf("unknown action: %s", control.Action))
+	}
+}
+
+func (s *streamSession) subscribe(key streamKey, rawParams map[string]string, record string) {
+	kind, ok := streamKinds[key.Kind]
+	if !ok || key.Host == "" {
+		s.sendStatus(key, StreamStatusError, fmt.Errorf("invalid subscription host: %q kind: %q", key.Host, key.Kind))
+		return
+	}
+
+	params := url.Values{}
+	for param, value := range rawParams {
+		params.Set(param, value)
+	}
+
+	s.mu.Lock()
+	if _, exists := s.subs[key]; exists {
+		s.mu.Unlock()
+		s.sendStatus(key, StreamStatusSubscribed, nil)
+		return
+	}
+
+	subCtx, subCancel := context.WithCancel(s.ctx)
+	sub := &streamSubscription{cancel: subCancel}
+	s.subs[key] = sub
+	s.mu.Unlock()
+
+	s.RunWSFunc(s.adapterReader(subCtx, key, kind, params, record, sub))
+}
+
+func (s *streamSession) unsubscribe(key streamKey) {
+	s.mu.Lock()
+	sub, ok := s.subs[key]
+	if ok {
+		delete(s.subs, key)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		s.sendStatus(key, StreamStatusError, fmt.Errorf("not subscribed"))
+		return
+	}
+
+	sub.cancel()
+	s.sendStatus(key, StreamStatusUnsubscribed, nil)
+}
+
+// remove deletes the subscription only if it hasn't already been replaced by a new subscribe.
+func (s *streamSession) remove(key streamKey, sub *streamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs[key] == sub {
+		delete(s.subs, key)
+	}
+}
+
+func (s *streamSession) setPaused(match streamKey, paused bool) {
+	s.mu.Lock()
+	matched := map[streamKey]*streamSubscription{}
+	for key, sub := range s.subs {
+		if (match.Host == "" || match.Host == key.Host) && (match.Kind == "" || match.Kind == key.Kind) {
+			matched[key] = sub
+		}
+	}
+	s.mu.Unlock()
+
+	for key, sub := range matched {
+		sub.paused.Store(paused)
+		if paused {
+			s.sendStatus(key, StreamStatusPaused, nil)
+			continue
+		}
+
+		s.send(StreamMessage{
+			Type:    StreamTypeStatus,
+			Host:    key.Host,
+			Kind:    key.Kind,
+			Status:  StreamStatusResumed,
+			Dropped: sub.dropped.Swap(0),
+		})
+	}
+}
+
+// startRecording records a subscription until it ends, messages keep the RelativeWebsocket shape so Replay handles both.
+// Recording is best effort, a failure is reported to the client without taking the subscription down.
+func (s *streamSession) startRecording(key streamKey, kind paths.KnownPath, name string) *wsRecorder {
+	header := RecordingHeader{
+		ID:      newRecordingID(),
+		Name:    name,
+		Kind:    strings.TrimPrefix(kind.Adapter(), "/"),
+		Hosts:   []string{key.Host},
+		User:    s.r.UserName,
+		TraceID: s.r.TraceID,
+		Started: time.Now(),
+	}
+
+	recorder, err := newWSRecorder(s.r, header)
+	if err != nil {
+		s.r.Log.Errorw("failed to start stream recording", "host", key.Host, "error", err)
+		s.sendStatus(key, StreamStatusError, fmt.Errorf("failed to start recording: %w", err))
+		return nil
+	}
+
+	s.r.Log.Infow("recording stream subscription", "host", key.Host, "kind", key.Kind, "recording", header.ID)
+	s.send(StreamMessage{
+		Type:      StreamTypeStatus,
+		Host:      key.Host,
+		Kind:      key.Kind,
+		Status:    StreamStatusRecording,
+		Recording: header.ID,
+	})
+	return recorder
+}
+
+// adapterReader dials the adapter and forwards messages until unsubscribed.
+// Dropped adapters are redialed with backoff, the subscription is only removed once we give up.
+// A non empty record name records everything the adapter sends, paused or not.
+func (s *streamSession) adapterReader(subCtx context.Context, key streamKey, kind paths.KnownPath, params url.Values, record string, sub *streamSubscription) tsymbiote.WebsocketFunc {
+	return func(shutdownCtx context.Context) {
+		defer sub.cancel()
+		// Tear the subscription down on server shutdown as well.
+		stopShutdown := context.AfterFunc(shutdownCtx, sub.cancel)
+		defer stopShutdown()
+
+		s.sendStatus(key, StreamStatusConnecting, nil)
+
+		conn, err := s.dialAdapterWebsocket(subCtx, s.r, key.Host, kind.Adapter(), params)
+		if err != nil {
+			// Unsubscribed while dialing, status was already sent.
+			if subCtx.Err() != nil {
+				return
+			}
+			s.r.Log.Errorw("failed to dial adapter", "host", key.Host, "error", err)
+			s.remove(key, sub)
+			s.sendStatus(key, StreamStatusError, err)
+			return
+		}
+
+		var recorder *wsRecorder
+		if record != "" {
+			recorder = s.startRecording(key, kind, record)
+		}
+		if recorder != nil {
+			defer func() {
+				err := recorder.Close()
+				if err != nil {
+					s.r.Log.Errorw("failed to close stream recording", "error", err)
+				}
+			}()
+		}
+
+		recordFunc := func(messageType int, hostMessage tsymbiote.WebsocketHostMessage) {
+			if recorder == nil {
+				return
+			}
+			message, err := json.Marshal(hostMessage)
+			if err == nil {
+				err = recorder.Record(tsymbiote.WebsocketMessage{Type: messageType, Message: message})
+			}
+			if err != nil {
+				s.r.Log.Errorw("failed to record stream message", "error", err)
+			}
+		}
+
+		forward := func(messageType int, message []byte) bool {
+			recordFunc(messageType, tsymbiote.WebsocketHostMessage{Host: key.Host, Message: message})
+
+			if sub.paused.Load() {
+				sub.dropped.Add(1)
+				return true
+			}
+
+			s.send(StreamMessage{
+				Type:    StreamTypeMessage,
+				Host:    key.Host,
+				Kind:    key.Kind,
+				Message: message,
+			})
+			return subCtx.Err() == nil
+		}
+
+		for {
+			s.sendStatus(key, StreamStatusSubscribed, nil)
+
+			err = readAdapterWebsocket(subCtx, s.r, conn, forward)
+			// We asked for this one to close, status was already sent.
+			if subCtx.Err() != nil {
+				return
+			}
+			s.sendStatus(key, StreamStatusDisconnected, err)
+			recordFunc(websocket.TextMessage, hostStatusMessage(key.Host, &tsymbiote.WebsocketStatus{
+				State: tsymbiote.WebsocketStateDisconnected,
+				Error: err.Error(),
+			}))
+
+			conn, err = s.redialAdapterWebsocket(subCtx, s.r, key.Host, kind.Adapter(), params, func(attempt int, delay time.Duration, lastErr error) {
+				message := StreamMessage{
+					Type:    StreamTypeStatus,
+					Host:    key.Host,
+					Kind:    key.Kind,
+					Status:  StreamStatusReconnecting,
+					Attempt: attempt,
+					RetryIn: delay,
+				}
+				if lastErr != nil {
+					message.Error = lastErr.Error()
+				}
+				s.send(message)
+			})
+			if err != nil {
+				if subCtx.Err() != nil {
+					return
+				}
+				s.remove(key, sub)
+				s.sendStatus(key, StreamStatusError, err)
+				return
+			}
+
+			recordFunc(websocket.TextMessage, hostStatusMessage(key.Host, &tsymbiote.WebsocketStatus{
+				State: tsymbiote.WebsocketStateReconnected,
+			}))
+		}
+	}
+}
+
+func (s *streamSession) clientReader(deathFunc func()) tsymbiote.WebsocketFunc {
+	return func(clientReadsCtx context.Context) {
+		// Close from read loop
+		defer s.r.WS.Close()
+
+		for {
+			select {
+			// shutdown of server
+			case <-clientReadsCtx.Done():
+				return
+			// Client died in write loop, just exit.
+			case <-s.ctx.Done():
+				return
+			default:
+				// Webui sends a ping every 5 seconds, give a little leeway.
+				s.r.WS.SetReadDeadline(time.Now().Add(consts.PingPongInterval + consts.WSWriteTimeout))
+				messageType, message, err := s.r.WS.ReadMessage()
+				if err != nil {
+					tsymbiote.LogWebsocketError(s.r, err)
+					// When we exit the client reader we no longer need the adapter(s)
+					deathFunc()
+					return
+				}
+
+				if messageType != websocket.TextMessage {
+					continue
+				}
+
+				if string(message) == "ping" {
+					select {
+					case s.out <- tsymbiote.WebsocketMessage{Type: websocket.TextMessage, Message: []byte("pong")}:
+					case <-s.ctx.Done():
+					}
+					continue
+				}
+
+				control := StreamControl{}
+				err = json.Unmarshal(message, &control)
+				if err != nil {
+					s.sendStatus(streamKey{}, StreamStatusError, fmt.Errorf("invalid control message: %w", err))
+					continue
+				}
+
+				s.handleControl(control)
+			}
+		}
+	}
+}
+
+func (s *streamSession) clientWriter(deathFunc func()) tsymbiote.WebsocketFunc {
+	return func(writerFuncCtx context.Context) {
+		// Subscriptions derive from the session context, this tears all of them down.
+		defer deathFunc()
+
+		for {
+			select {
+			// Server shutdown, close the client.
+			case <-writerFuncCtx.Done():
+				err := s.r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
+				if err != nil {
+					tsymbiote.LogWebsocketError(s.r, err)
+				}
+				return
+			// client died in read loop
+			case <-s.ctx.Done():
+				return
+			case msg := <-s.out:
+				s.r.WS.SetWriteDeadline(time.Now().Add(consts.WSWriteTimeout))
+				err := s.r.WS.WriteMessage(msg.Type, msg.Message)
+				if err != nil {
+					tsymbiote.LogWebsocketError(s.r, err)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package tsymbiotewebui
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/types/views"
+)
+
+func TestTSymbioteWebUI(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	RunSpecs(t, "TSymbiote WebUI Suite")
+}
+
+// newTestFleetHost builds a fleet host as collectFleet would, results are marshalled the way adapters return them.
+func newTestFleetHost(host string, status *ipnstate.Status, results map[paths.KnownPath]any) *fleetHost {
+	fleetHost := &fleetHost{Host: host, Adapter: host + "-adapter", Status: status, results: map[paths.KnownPath]json.RawMessage{}}
+	for path, result := range results {
+		raw, err := json.Marshal(result)
+		Expect(err).NotTo(HaveOccurred())
+		fleetHost.results[path] = raw
+	}
+	return fleetHost
+}
+
+func prefixes(raw ...string) *views.Slice[netip.Prefix] {
+	parsed := make([]netip.Prefix, 0, len(raw))
+	for _, prefix := range raw {
+		parsed = append(parsed, netip.MustParsePrefix(prefix))
+	}
+	slice := views.SliceOf(parsed)
+	return &slice
+}
@@ -0,0 +1,106 @@
+package tsymbiotewebui
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	"tailscale.com/tailcfg"
+)
+
+// TailnetLockReport is the fleet wide view of Tailnet Lock, nodes are named by host when they are in the fleet
+// and by DNS name otherwise.
+type TailnetLockReport struct {
+	Hosts []types.TailnetLockStatus `json:"hosts"`
+	// LockedOut hosts have lock enabled but no valid signature, locked peers drop their traffic.
+	LockedOut []string `json:"lockedOut"`
+	// UntrustedSignature hosts were signed by a key that is no longer trusted.
+	UntrustedSignature []string `json:"untrustedSignature"`
+	// Disabled hosts don't enforce lock while others in the fleet do.
+	Disabled []string `json:"disabled"`
+	// FilteredBy maps every filtered node to the hosts that drop it.
+	FilteredBy map[string][]string `json:"filteredBy"`
+	// Heads groups hosts by authority head, only set when hosts disagree, IE: one hasn't synced the latest change.
+	Heads map[string][]string `json:"heads,omitempty"`
+}
+
+// TailnetLock collects the network lock status of every host and works out which nodes are filtered and by whom.
+func (t *TSymbioteUIServer) TailnetLock(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	fleet, err := t.collectFleet(r.Context(), r, r.URL.Query().Get("selector"), paths.TailnetLock)
+	if err != nil {
+		r.Log.Errorw("failed to collect fleet", "error", err)
+		r.SetStatusCode(w, resolveStatus(err))
+		return
+	}
+
+	t.WriteJson(w, r, buildTailnetLockReport(fleet))
+}
+
+func buildTailnetLockReport(fleet []*fleetHost) *TailnetLockReport {
+	report := &TailnetLockReport{
+		Hosts:              []types.TailnetLockStatus{},
+		LockedOut:          []string{},
+		UntrustedSignature: []string{},
+		Disabled:           []string{},
+		FilteredBy:         map[string][]string{},
+	}
+
+	hostNames := map[tailcfg.StableNodeID]string{}
+	for _, host := range fleet {
+		if host.Status != nil && host.Status.Self != nil {
+			hostNames[host.Status.Self.ID] = host.Host
+		}
+	}
+
+	heads := map[string][]string{}
+	anyEnabled := false
+	for _, host := range fleet {
+		lock := types.TailnetLockStatus{}
+		err := host.Decode(paths.TailnetLock, &lock)
+		lock.Host = host.Host
+		if host.Error != "" || err != nil {
+			lock.Error = host.Error
+			if lock.Error == "" {
+				lock.Error = err.Error()
+			}
+			report.Hosts = append(report.Hosts, lock)
+			continue
+		}
+		report.Hosts = append(report.Hosts, lock)
+
+		if !lock.Enabled {
+			report.Disabled = append(report.Disabled, host.Host)
+			continue
+		}
+		anyEnabled = true
+
+		if lock.LockedOut {
+			report.LockedOut = append(report.LockedOut, host.Host)
+		}
+		if lock.SignatureKind != "" && !lock.SignedByTrustedKey {
+			report.UntrustedSignature = append(report.UntrustedSignature, host.Host)
+		}
+		heads[lock.Head] = append(heads[lock.Head], host.Host)
+
+		for _, peer := range lock.FilteredPeers {
+			name, ok := hostNames[peer.StableID]
+			if !ok {
+				name = peer.Name
+			}
+			if !slices.Contains(report.FilteredBy[name], host.Host) {
+				report.FilteredBy[name] = append(report.FilteredBy[name], host.Host)
+			}
+		}
+	}
+
+	// A fleet without lock isn't misconfigured, only call out hosts that are behind the rest.
+	if !anyEnabled {
+		report.Disabled = []string{}
+	}
+	if len(heads) > 1 {
+		report.Heads = heads
+	}
+	return report
+}
@@ -0,0 +1,65 @@
+package tsymbiotewebui
+
+import (
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/ipn/ipnstate"
+	"tailscale.com/tailcfg"
+)
+
+var _ = Describe("buildTailnetLockReport", func() {
+	lockHost := func(host string, id string, lock types.TailnetLockStatus) *fleetHost {
+		status := &ipnstate.Status{Self: &ipnstate.PeerStatus{ID: tailcfg.StableNodeID(id)}}
+		return newTestFleetHost(host, status, map[paths.KnownPath]any{paths.TailnetLock: lock})
+	}
+
+	It("Should find locked out hosts, untrusted signatures, filtered peers and diverged heads", func() {
+		down := newTestFleetHost("t4", nil, nil)
+		down.Error = "adapter unreachable"
+
+		report := buildTailnetLockReport([]*fleetHost{
+			lockHost("t1", "s1", types.TailnetLockStatus{
+				Enabled:            true,
+				Head:               "aa",
+				SignatureKind:      "direct",
+				SignedByTrustedKey: true,
+				FilteredPeers: []types.TailnetLockPeer{
+					{Name: "t3.example.ts.net.", StableID: "s3"},
+					{Name: "laptop.example.ts.net.", StableID: "s9"},
+				},
+			}),
+			lockHost("t2", "s2", types.TailnetLockStatus{
+				Enabled:       true,
+				Head:          "bb",
+				LockedOut:     true,
+				SignatureKind: "direct",
+				FilteredPeers: []types.TailnetLockPeer{{Name: "t3.example.ts.net.", StableID: "s3"}},
+			}),
+			lockHost("t3", "s3", types.TailnetLockStatus{}),
+			down,
+		})
+
+		Expect(report.Hosts).To(HaveLen(4))
+		Expect(report.Hosts[3].Error).To(Equal("adapter unreachable"))
+		Expect(report.LockedOut).To(Equal([]string{"t2"}))
+		Expect(report.UntrustedSignature).To(Equal([]string{"t2"}))
+		Expect(report.Disabled).To(Equal([]string{"t3"}))
+		Expect(report.FilteredBy).To(Equal(map[string][]string{
+			"t3":                     {"t1", "t2"},
+			"laptop.example.ts.net.": {"t1"},
+		}))
+		Expect(report.Heads).To(Equal(map[string][]string{"aa": {"t1"}, "bb": {"t2"}}))
+	})
+
+	It("Should not call out disabled hosts when no host uses lock", func() {
+		report := buildTailnetLockReport([]*fleetHost{
+			lockHost("t1", "s1", types.TailnetLockStatus{}),
+			lockHost("t2", "s2", types.TailnetLockStatus{}),
+		})
+
+		Expect(report.Disabled).To(BeEmpty())
+		Expect(report.Heads).To(BeNil())
+	})
+})
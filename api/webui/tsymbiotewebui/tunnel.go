@@ -0,0 +1,156 @@
+package tsymbiotewebui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dhouti/tsymbiote/api/shared/consts"
+	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
+	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/hashicorp/yamux"
+	"tailscale.com/client/tailscale/v2"
+	"tailscale.com/tailcfg"
+)
+
+// tunnelAdapterHeader names the adapter on a tunnel, outside of dev mode tunnelAuth replaces it with the node's MagicDNS label.
+// tunnelNodeHeader is the stable ID of the node behind it, set only by tunnelAuth.
+const (
+	tunnelAdapterHeader = "ts-adapter"
+	tunnelNodeHeader    = "ts-adapter-node"
+)
+
+// tunnelAuth only lets tagged adapters open a tunnel, under a name no other adapter or relay is listed by.
+func (t *TSymbioteUIServer) tunnelAuth(next tsymbiote.HandlerFunc) tsymbiote.HandlerFunc {
+	return func(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+
+		resp, err := t.Local().WhoIs(r.Context(), r.RemoteAddr)
+		if err != nil {
+			r.Log.Errorw("failed to get whois", "error", err)
+			r.SetStatusCode(w, http.StatusInternalServerError)
+			return
+		}
+
+		if !slices.Contains(resp.Node.Tags, adapterTag) {
+			r.Log.Errorw("tunnel rejected, missing adapter tag", "node", resp.Node.ComputedName)
+			r.SetStatusCode(w, http.StatusForbidden)
+			return
+		}
+
+		devices, err := t.GetDevices()
+		if err != nil {
+			r.Log.Errorw("failed to get devices", "error", err)
+			r.SetStatusCode(w, http.StatusInternalServerError)
+			return
+		}
+
+		name := magicDNSLabel(resp.Node.Name)
+		if tunnelNameTaken(devices, name, resp.Node.StableID) {
+			r.Log.Errorw("tunnel rejected, name belongs to another device", "adapter", name, "node", resp.Node.StableID)
+			r.SetStatusCode(w, http.StatusForbidden)
+			return
+		}
+
+		r.Header.Set(tunnelAdapterHeader, name)
+		r.Header.Set(tunnelNodeHeader, string(resp.Node.StableID))
+		next(w, r)
+	}
+}
+
+// magicDNSLabel is the first label of a MagicDNS name, IE: web-1 for web-1.example.ts.net.
+// Control keeps it unique in the tailnet, unlike the hostname a node reports.
+func magicDNSLabel(name string) string {
+	label, _, _ := strings.Cut(strings.TrimSuffix(name, "."), ".")
+	return label
+}
+
+// tunnelNameTaken reports whether an adapter or relay other than node is listed under name,
+// calls we make to that name would otherwise go over the tunnel instead of to the device.
+func tunnelNameTaken(devices []tailscale.Device, name string, node tailcfg.StableNodeID) bool {
+	for _, device := range devices {
+		if !slices.Contains(device.Tags, adapterTag) && !slices.Contains(device.Tags, relayTag) {
+			continue
+		}
+		if device.NodeID == string(node) {
+			continue
+		}
+		if device.Hostname == name || magicDNSLabel(device.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Tunnel accepts a reverse connection from an adapter that can't be dialed over the tailnet.
+// The websocket carries a yamux session, every call to the adapter opens a new stream on it.
+func (t *TSymbioteUIServer) Tunnel(w http.ResponseWriter, r *tsymbiote.HTTPRequest) {
+	adapter := r.Header.Get(tunnelAdapterHeader)
+	if adapter == "" {
+		r.Log.Error("tunnel opened without an adapter name, closing")
+		r.WS.Close()
+		return
+	}
+
+	session, err := yamux.Client(tsymbiote.NewWebsocketConn(r.WS), nil)
+	if err != nil {
+		r.Log.Errorw("failed to start tunnel session", "adapter", adapter, "error", err)
+		r.WS.Close()
+		return
+	}
+	node := r.Header.Get(tunnelNodeHeader)
+	err = t.SetTunnel(adapter, node, session)
+	if err != nil {
+		r.Log.Errorw("tunnel rejected", "adapter", adapter, "node", node, "error", err)
+		session.Close()
+		r.WS.Close()
+		return
+	}
+	r.Log.Infow("adapter tunnel connected", "adapter", adapter, "node", node)
+
+	t.RunWSFunc(func(shutdownCtx context.Context) {
+		defer t.DeleteTunnel(adapter, session)
+		defer session.Close()
+
+		// Learn the adapter's host right away instead of waiting for the next device refresh.
+		t.learnTunnelHost(shutdownCtx, adapter)
+
+		select {
+		case <-shutdownCtx.Done():
+		case <-session.CloseChan():
+		}
+		r.Log.Infow("adapter tunnel closed", "adapter", adapter)
+	})
+}
+
+func (t *TSymbioteUIServer) learnTunnelHost(ctx context.Context, adapter string) {
+	r, err := t.backgroundRequest(ctx, "")
+	if err != nil {
+		t.Log.Errorw("failed to build tunnel request", "adapter", adapter, "error", err)
+		return
+	}
+
+	outgoingctx, outgoingcancel := context.WithTimeout(ctx, consts.OutgoingRequestTimeout)
+	defer outgoingcancel()
+
+	resp, err := t.CallAdapter(outgoingctx, r, "POST", adapter, paths.Status.Adapter(), nil)
+	if err != nil {
+		r.Log.Errorw("failed to get status over tunnel", "adapter", adapter, "error", err)
+		return
+	}
+	defer resp.Close()
+
+	status := struct {
+		Self struct {
+			HostName string
+		}
+	}{}
+	err = json.NewDecoder(resp).Decode(&status)
+	if err != nil || status.Self.HostName == "" {
+		r.Log.Errorw("failed to decode status over tunnel", "adapter", adapter, "error", err)
+		return
+	}
+
+	t.SetKnownHost(status.Self.HostName, adapter)
+}
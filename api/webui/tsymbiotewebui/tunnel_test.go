@@ -0,0 +1,32 @@
+package tsymbiotewebui
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"tailscale.com/client/tailscale/v2"
+)
+
+var _ = Describe("tunnelNameTaken", func() {
+	devices := []tailscale.Device{
+		{NodeID: "n1", Hostname: "web-1", Name: "web-1.example.ts.net", Tags: []string{adapterTag}},
+		{NodeID: "n2", Hostname: "relay", Name: "relay-2.example.ts.net", Tags: []string{relayTag}},
+		{NodeID: "n3", Hostname: "laptop", Name: "laptop.example.ts.net"},
+	}
+
+	It("Should refuse a tunnel named after another node's adapter or relay", func() {
+		Expect(tunnelNameTaken(devices, "web-1", "n9")).To(BeTrue())
+		Expect(tunnelNameTaken(devices, "relay", "n9")).To(BeTrue())
+		Expect(tunnelNameTaken(devices, "relay-2", "n9")).To(BeTrue())
+	})
+
+	It("Should accept the node's own name and names only untagged devices use", func() {
+		Expect(tunnelNameTaken(devices, "web-1", "n1")).To(BeFalse())
+		Expect(tunnelNameTaken(devices, "laptop", "n9")).To(BeFalse())
+		Expect(tunnelNameTaken(devices, "web-2", "n9")).To(BeFalse())
+	})
+
+	It("Should name tunnels by the MagicDNS label", func() {
+		Expect(magicDNSLabel("web-1-1.example.ts.net.")).To(Equal("web-1-1"))
+		Expect(magicDNSLabel("web-1")).To(Equal("web-1"))
+	})
+})
@@ -13,7 +13,9 @@ import (
 	"github.com/dhouti/tsymbiote/api/shared/consts"
 	"github.com/dhouti/tsymbiote/api/shared/consts/paths"
 	"github.com/dhouti/tsymbiote/api/shared/tsymbiote"
+	"github.com/dhouti/tsymbiote/api/webui/client"
 	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
 )
 
 // RelativeWebsocket routes to
@@ -48,6 +50,11 @@ func (t *TSymbioteUIServer) RelativeWebsocket(w http.ResponseWriter, r *tsymbiot
 	// For large amounts of hosts this may need to be moved into the websocket connection.
 	targets := strings.Split(rawTargets, ",")
 
+	// Everything but hosts is forwarded to the adapters, IE: bus event filters.
+	forwardParams := r.URL.Query()
+	forwardParams.Del("hosts")
+	forwardParams.Del("record")
+
 	if len(targets) == 0 {
 		r.Log.Error("zero targets provided, closing")
 		r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
@@ -55,104 +62,242 @@ func (t *TSymbioteUIServer) RelativeWebsocket(w http.ResponseWriter, r *tsymbiot
 		r.WS.Close()
 	}
 
+	// Used to signal kill all adapter sockets across routines
+	clientDeathCtx, deathFunc := context.WithCancel(context.Background())
+
 	// Make a buffered channel the size of the amount of targets we have.
 	msg := make(chan tsymbiote.WebsocketMessage, len(targets))
 	// slice of funcs we're going to start after loop
 	readerFuncs := make([]tsymbiote.WebsocketFunc, 0, len(targets))
-	// map of websocket connections we're going to close in the event of client socket death
-	adapterConns := map[string]*websocket.Conn{}
 	// Channel for tracking death of adapters
 	dead := make(chan string, len(targets))
 
 	for _, target := range targets {
 
-		// Get translated hostname
-		adapterHost, ok := t.GetAdapter(target)
-		if !ok || adapterHost == "" {
-			r.Log.Errorw("failed to find adatpter for host", "host", adapterHost)
-			return
+		// A host that fails its first dial goes through the same redial and dead path as one that drops later.
+		adapterConn, err := t.dialAdapterWebsocket(r.Context(), r, target, targetPath, forwardParams)
+		if err != nil {
+			r.Log.Errorw("failed to dial adapter", "host", target, "error", err)
 		}
 
-		url := url.URL{Scheme: "ws", Host: fmt.Sprintf("%s:3621", adapterHost), Path: targetPath}
-		wsDialer := &websocket.Dialer{
-			HandshakeTimeout: 45 * time.Second,
-			NetDial: func(network string, address string) (net.Conn, error) {
-				return t.TSNet().Dial(r.Context(), network, address)
-			},
-		}
+		// adapter reader goroutine per target sending messages at a central writer
+		readerFuncs = append(readerFuncs, t.adapterWebsocketReader(clientDeathCtx, r, target, targetPath, forwardParams, msg, dead, adapterConn, err))
+	}
 
-		// Propagate trace-id to downstream websockets.
-		traceHeaders := http.Header{}
-		traceHeaders.Set("trace-id", r.TraceID)
-		// Do the same with username, fetched when we grab auth details.
-		traceHeaders.Set("ts-username", r.UserName)
+	// Optionally record everything sent to the client so it can be replayed later.
+	var recorder *wsRecorder
+	if urlparams.Has("record") {
+		header := RecordingHeader{
+			ID:      newRecordingID(),
+			Name:    urlparams.Get("record"),
+			Kind:    strings.TrimPrefix(targetPath, "/"),
+			Hosts:   targets,
+			User:    r.UserName,
+			TraceID: r.TraceID,
+			Started: time.Now(),
+		}
 
-		adapterConn, _, err := wsDialer.DialContext(r.Context(), url.String(), traceHeaders)
+		var err error
+		recorder, err = newWSRecorder(r, header)
 		if err != nil {
-			r.Log.Errorw("failed to dial adapter", "error", err)
-			return
+			// Recording is best effort, don't take the stream down with it.
+			r.Log.Errorw("failed to start websocket recording", "error", err)
+		} else {
+			r.Log.Infow("recording websocket session", "recording", header.ID)
 		}
-
-		adapterConn.SetPongHandler(func(string) error {
-			adapterConn.SetReadDeadline(time.Now().Add(consts.PingPongTimeout))
-			return nil
-		})
-
-		adapterConns[target] = adapterConn
-
-		// adapter reader goroutine per target sending messages at a central writer
-		readerFuncs = append(readerFuncs, adapterWebsocketReader(r, target, msg, dead, adapterConn))
 	}
 
-	// Used to signal kill all adapter sockets across routines
-	clientDeathCtx, deathFunc := context.WithCancel(context.Background())
-
 	// Run goroutines using manager so we can inject a non-request scoped context and signal/track shutdown events.
 	for _, readerFunc := range readerFuncs {
 		t.RunWSFunc(readerFunc)
 	}
 	t.RunWSFunc(clientWebsocketReader(clientDeathCtx, deathFunc, r, msg))
-	t.RunWSFunc(clientWebsocketWriter(clientDeathCtx, deathFunc, r, adapterConns, msg, dead))
+	t.RunWSFunc(clientWebsocketWriter(clientDeathCtx, deathFunc, r, targets, msg, dead, recorder))
 }
 
-func adapterWebsocketReader(r *tsymbiote.HTTPRequest, target string, ws chan tsymbiote.WebsocketMessage, dead chan string, conn *websocket.Conn) tsymbiote.WebsocketFunc {
+// dialAdapterWebsocket translates a host to its adapter and opens a websocket to the adapter path.
+// Any params are passed along as query params, trace-id and username are propagated through headers.
+func (t *TSymbioteUIServer) dialAdapterWebsocket(ctx context.Context, r *tsymbiote.HTTPRequest, host string, targetPath string, params url.Values) (*websocket.Conn, error) {
+	// Get translated hostname
+	adapterHost, ok := t.GetAdapter(host)
+	if !ok || adapterHost == "" {
+		return nil, fmt.Errorf("failed to find adapter for host: %s", host)
+	}
+
+	device, prefix := client.SplitAdapter(adapterHost)
+	url := url.URL{Scheme: "ws", Host: fmt.Sprintf("%s:%s", device, viper.GetString("adapter-port")), Path: prefix + targetPath, RawQuery: params.Encode()}
+	wsDialer := &websocket.Dialer{
+		HandshakeTimeout: 45 * time.Second,
+		NetDial: func(network string, address string) (net.Conn, error) {
+			return t.DialAdapter(ctx, network, address)
+		},
+	}
+
+	// Propagate trace-id to downstream websockets.
+	traceHeaders := http.Header{}
+	traceHeaders.Set("trace-id", r.TraceID)
+	// Do the same with username, fetched when we grab auth details.
+	traceHeaders.Set("ts-username", r.UserName)
+
+	adapterConn, _, err := wsDialer.DialContext(ctx, url.String(), traceHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	adapterConn.SetPongHandler(func(string) error {
+		adapterConn.SetReadDeadline(time.Now().Add(consts.PingPongTimeout))
+		return nil
+	})
+
+	return adapterConn, nil
+}
+
+// adapterWebsocketReader forwards messages from one adapter to the central writer.
+// When the adapter goes away it is redialed with backoff, the host is only reported dead once we give up.
+// conn is nil when the first dial failed with err, the adapter is then redialed straight away.
+func (t *TSymbioteUIServer) adapterWebsocketReader(clientDeathCtx context.Context, r *tsymbiote.HTTPRequest, target string, targetPath string, params url.Values, ws chan tsymbiote.WebsocketMessage, dead chan string, conn *websocket.Conn, err error) tsymbiote.WebsocketFunc {
 	return func(adapterReaderCtx context.Context) {
-		// Close from read loop
-		defer conn.Close()
-		for {
+		// Stop on either server shutdown or client death.
+		readerCtx, readerCancel := context.WithCancel(clientDeathCtx)
+		defer readerCancel()
+		stopShutdown := context.AfterFunc(adapterReaderCtx, readerCancel)
+		defer stopShutdown()
+
+		send := func(messageType int, hostMessage tsymbiote.WebsocketHostMessage) bool {
+			newMessage, err := json.Marshal(hostMessage)
+			if err != nil {
+				r.Log.Errorw("failed to marshal websocket message", "error", err)
+				return true
+			}
+
+			// Throw the message in a chan for writing in other goroutine.
 			select {
-			case <-adapterReaderCtx.Done():
-				return
-			default:
-				messageType, message, err := conn.ReadMessage()
-				if err != nil {
-					tsymbiote.LogWebsocketError(r, err)
-					// Signal tracker about death
-					dead <- target
+			case ws <- tsymbiote.WebsocketMessage{Type: messageType, Message: newMessage}:
+				return true
+			case <-readerCtx.Done():
+				return false
+			}
+		}
+
+		for {
+			if conn != nil {
+				err = readAdapterWebsocket(readerCtx, r, conn, func(messageType int, message []byte) bool {
+					return send(messageType, tsymbiote.WebsocketHostMessage{Host: target, Message: message})
+				})
+				if readerCtx.Err() != nil {
 					return
 				}
+			}
 
-				tmp := tsymbiote.WebsocketHostMessage{
-					Host:    target,
-					Message: message,
-				}
+			send(websocket.TextMessage, hostStatusMessage(target, &tsymbiote.WebsocketStatus{
+				State: tsymbiote.WebsocketStateDisconnected,
+				Error: err.Error(),
+			}))
 
-				newMessage, err := json.Marshal(tmp)
-				if err != nil {
-					r.Log.Errorw("failed to marshal websocket message", "error", err)
-					continue
+			conn, err = t.redialAdapterWebsocket(readerCtx, r, target, targetPath, params, func(attempt int, delay time.Duration, lastErr error) {
+				status := &tsymbiote.WebsocketStatus{
+					State:   tsymbiote.WebsocketStateReconnecting,
+					Attempt: attempt,
+					RetryIn: delay,
 				}
-
-				// Throw the message in a chan for writing in other goroutine.
-				ws <- tsymbiote.WebsocketMessage{
-					Type:    messageType,
-					Message: newMessage,
+				if lastErr != nil {
+					status.Error = lastErr.Error()
+				}
+				send(websocket.TextMessage, hostStatusMessage(target, status))
+			})
+			if err != nil {
+				if readerCtx.Err() != nil {
+					return
 				}
+				r.Log.Errorw("failed to reconnect to adapter", "host", target, "error", err)
+				// Signal tracker about death
+				dead <- target
+				return
+			}
+
+			send(websocket.TextMessage, hostStatusMessage(target, &tsymbiote.WebsocketStatus{
+				State: tsymbiote.WebsocketStateReconnected,
+			}))
+		}
+	}
+}
+
+// readAdapterWebsocket pumps messages from an adapter into forward until the connection dies or ctx is canceled.
+// The connection is always closed on return.
+func readAdapterWebsocket(ctx context.Context, r *tsymbiote.HTTPRequest, conn *websocket.Conn, forward func(messageType int, message []byte) bool) error {
+	// Close from read loop
+	defer conn.Close()
+
+	// Unblock the read on cancel.
+	stop := context.AfterFunc(ctx, func() {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	pingCtx, pingCancel := context.WithCancel(ctx)
+	defer pingCancel()
+	go adapterPinger(pingCtx, r, conn)
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil {
+				tsymbiote.LogWebsocketError(r, err)
+			}
+			return err
+		}
+
+		if !forward(messageType, message) {
+			return ctx.Err()
+		}
+	}
+}
+
+// adapterPinger keeps the adapter read deadline moving, the pong handler is set when dialing.
+func adapterPinger(ctx context.Context, r *tsymbiote.HTTPRequest, conn *websocket.Conn) {
+	ticker := time.NewTicker(consts.PingPongInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(consts.WSWriteTimeout))
+			if err != nil {
+				tsymbiote.LogWebsocketError(r, err)
+				// Not allowed to play ping pong with this one anymore, force the reader to notice.
+				conn.SetReadDeadline(time.Now())
+				return
 			}
 		}
 	}
 }
 
+// hostStatusMessage builds a status message for a host, Message stays human readable for clients that only print it.
+func hostStatusMessage(host string, status *tsymbiote.WebsocketStatus) tsymbiote.WebsocketHostMessage {
+	var text string
+	switch status.State {
+	case tsymbiote.WebsocketStateClosed:
+		text = "websocket closed"
+	case tsymbiote.WebsocketStateReconnecting:
+		text = fmt.Sprintf("reconnecting (attempt %d/%d) in %s", status.Attempt, consts.WSReconnectMaxAttempts, status.RetryIn.Round(time.Millisecond))
+	default:
+		text = fmt.Sprintf("adapter %s", status.State)
+	}
+
+	if status.Error != "" {
+		text = fmt.Sprintf("%s: %s", text, status.Error)
+	}
+
+	return tsymbiote.WebsocketHostMessage{
+		Host:    host,
+		Message: []byte(text),
+		Status:  status,
+	}
+}
+
 func clientWebsocketReader(clientDeathCtx context.Context, deathFunc func(), r *tsymbiote.HTTPRequest, ws chan tsymbiote.WebsocketMessage) tsymbiote.WebsocketFunc {
 	return func(clientReadsCtx context.Context) {
 		// Close from read loop
@@ -190,8 +335,17 @@ func clientWebsocketReader(clientDeathCtx context.Context, deathFunc func(), r *
 	}
 }
 
-func clientWebsocketWriter(clientDeathCtx context.Context, deathFunc func(), r *tsymbiote.HTTPRequest, adapterConns map[string]*websocket.Conn, msg chan tsymbiote.WebsocketMessage, dead chan string) tsymbiote.WebsocketFunc {
+func clientWebsocketWriter(clientDeathCtx context.Context, deathFunc func(), r *tsymbiote.HTTPRequest, targets []string, msg chan tsymbiote.WebsocketMessage, dead chan string, recorder *wsRecorder) tsymbiote.WebsocketFunc {
 	return func(writerFuncCtx context.Context) {
+		if recorder != nil {
+			defer func() {
+				err := recorder.Close()
+				if err != nil {
+					r.Log.Errorw("failed to close websocket recording", "error", err)
+				}
+			}()
+		}
+
 		closeClientFunc := func() {
 			err := r.WS.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
 			if err != nil {
@@ -200,71 +354,57 @@ func clientWebsocketWriter(clientDeathCtx context.Context, deathFunc func(), r *
 			}
 		}
 
-		closeReadersFunc := func(remainingAdapters map[string]*websocket.Conn) {
-			for _, remainingAdapterConn := range remainingAdapters {
-				err := remainingAdapterConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
+		writeFunc := func(msg tsymbiote.WebsocketMessage) {
+			r.WS.SetWriteDeadline(time.Now().Add(consts.WSWriteTimeout))
+			err := r.WS.WriteMessage(msg.Type, msg.Message)
+			if err != nil {
+				tsymbiote.LogWebsocketError(r, err)
+				// When we exit the client reader we no longer need the adapter(s)
+				// Restart loop so we catch the context
+				deathFunc()
+				return
+			}
+
+			// Pongs are just keepalives, everything else is worth replaying.
+			if recorder != nil && string(msg.Message) != "pong" {
+				err = recorder.Record(msg)
 				if err != nil {
-					// Wouldn't expect an error here
-					tsymbiote.LogWebsocketError(r, err)
+					r.Log.Errorw("failed to record websocket message", "error", err)
 				}
 			}
 		}
 
-		ticker := time.NewTicker(consts.PingPongInterval)
-		defer ticker.Stop()
-
-		livingAdapters := adapterConns
+		// Adapter readers ping and redial their own connections, we only track who is left.
+		livingAdapters := map[string]bool{}
+		for _, target := range targets {
+			livingAdapters[target] = true
+		}
 
 		for {
 			select {
-			// Server shutdown, send close to adapters and client.
+			// Server shutdown, adapter readers see the same context. Close the client.
 			case <-writerFuncCtx.Done():
-				closeReadersFunc(livingAdapters)
 				closeClientFunc()
 				return
-			// client died in read loop, kill all adapters
+			// client died in read loop, adapter readers are watching the same context.
 			case <-clientDeathCtx.Done():
-				closeReadersFunc(livingAdapters)
 				return
-			case <-ticker.C:
-				// ping adapters to see if they're alive
-				for adapter, adapterConn := range livingAdapters {
-					// Don't block the main writer, shove these in a background task per adapter.
-					// Writes here will corrupt the connection on fail faster than they re-run anyway.
-					go func() {
-						err := adapterConn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(consts.WSWriteTimeout))
-						if err != nil {
-							tsymbiote.LogWebsocketError(r, err)
-							adapterConn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(consts.WSWriteTimeout))
-							// Not allowed to play ping pong with this one anymore
-							// Fire the adapter key at the tracker
-							dead <- adapter
-						}
-					}()
-
-				}
 			// remove adapter from map when key received
 			case adapter := <-dead:
 				delete(livingAdapters, adapter)
 
-				tmp := tsymbiote.WebsocketHostMessage{
-					Host:    adapter,
-					Message: []byte("websocket closed"),
-				}
-
-				newMessage, err := json.Marshal(tmp)
+				newMessage, err := json.Marshal(hostStatusMessage(adapter, &tsymbiote.WebsocketStatus{
+					State: tsymbiote.WebsocketStateClosed,
+				}))
 				if err != nil {
 					r.Log.Errorw("failed to marshal websocket close message for client", "error", err)
 					continue
 				}
 
-				replyMessage := tsymbiote.WebsocketMessage{
+				writeFunc(tsymbiote.WebsocketMessage{
 					Type:    websocket.TextMessage,
 					Message: newMessage,
-				}
-
-				// Send to channel even though it's consumed right below, otherwise duplicated logic
-				msg <- replyMessage
+				})
 
 			case msg := <-msg:
 				// Because browers are silly and don't let us manually send ping frames we're adding a custom ping handler.
@@ -273,15 +413,7 @@ func clientWebsocketWriter(clientDeathCtx context.Context, deathFunc func(), r *
 					msg.Message = []byte("pong")
 				}
 
-				r.WS.SetWriteDeadline(time.Now().Add(consts.WSWriteTimeout))
-				err := r.WS.WriteMessage(msg.Type, msg.Message)
-				if err != nil {
-					tsymbiote.LogWebsocketError(r, err)
-					// When we exit the client reader we no longer need the adapter(s)
-					// Restart loop so we catch the context
-					deathFunc()
-					continue
-				}
+				writeFunc(msg)
 			}
 
 			// No more adapters, kill the client.
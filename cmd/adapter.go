@@ -37,7 +37,9 @@ func init() {
 	adapterCmd.PersistentFlags().String("allowed-tag", "tag:tsymbiote-webui", "Used to prevent access from sources that are not the web-ui. This cannot be an empty string.")
 	adapterCmd.PersistentFlags().StringP("port", "p", "3621", "The port to expose the service on.")
 	adapterCmd.PersistentFlags().Bool("dev", false, "Set true to enable dev, runs the backend in HTTP for local dev.")
-	adapterCmd.PersistentFlags().String("socket", "", "path to tailscaled socket")
-	adapterCmd.PersistentFlags().BoolP("discover-socket", "d", false, "Set true to automatically discover socket path (meant for k8s sidecar deployment)")
+	adapterCmd.PersistentFlags().StringSlice("socket", []string{}, "paths to tailscaled sockets, name=path names the instance. More than one serves each as its own host.")
+	adapterCmd.PersistentFlags().BoolP("discover-socket", "d", false, "Set true to automatically discover socket paths (meant for k8s sidecar deployment), every socket found is served as its own host")
+	adapterCmd.PersistentFlags().String("reverse-connect", "", "WebUI host:port to dial out to, the adapter API is also served over this connection for hosts that can't accept inbound tailnet connections.")
+	adapterCmd.PersistentFlags().Bool("behind-relay", false, "Set true to serve the adapter API on the pod network for a tsymbiote relay instead of joining the tailnet, requires TSYMBIOTE_RELAY_TOKEN.")
 	adapterCmd.PersistentFlags().Bool("logout", true, "true will call logout on exit, this will expire the key or delete if it's ephemeral")
 }
@@ -0,0 +1,46 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/dhouti/tsymbiote/api/relay/tsymbioterelay"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// relayCmd represents the relay command
+var relayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "A single tsnet service that fronts every adapter in a cluster.",
+	Long: `A single tsnet service that fronts every adapter in a cluster.
+Adapters run with --behind-relay and are found with a label selector or a static list, the WebUI sees each one as its own host.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		err := viper.BindPFlags(cmd.PersistentFlags())
+		if err != nil {
+			panic(err)
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Setup dependencies
+		tsymbiote := tsymbioterelay.NewTSymbioteRelay()
+		if tsymbiote != nil {
+			// Start
+			tsymbiote.ListenAndServe()
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(relayCmd)
+	relayCmd.PersistentFlags().String("hostname-prefix", "tsymbiote-relay", "A prefix to assign to the tsnet service hostname.")
+	relayCmd.PersistentFlags().String("hostname", "", "Used to set a static hostname. If not set hostname-prefix will be used.")
+	relayCmd.PersistentFlags().String("allowed-tag", "tag:tsymbiote-webui", "Used to prevent access from sources that are not the web-ui. This cannot be an empty string.")
+	relayCmd.PersistentFlags().StringP("port", "p", "3621", "The port to expose the service on.")
+	relayCmd.PersistentFlags().Bool("dev", false, "Set true to enable dev, runs the backend in HTTP for local dev.")
+	relayCmd.PersistentFlags().StringSlice("targets", []string{}, "A comma separated list of adapters to relay IE: web-0=10.0.0.5:3621,10.0.0.6:3621, takes precedence over label-selector.")
+	relayCmd.PersistentFlags().String("label-selector", "", "A Kubernetes label selector for pods running an adapter with --behind-relay.")
+	relayCmd.PersistentFlags().String("namespace", "", "The namespace to discover adapter pods in, defaults to the relay's own namespace.")
+	relayCmd.PersistentFlags().String("adapter-port", "3621", "The port adapters behind the relay are running on, they must all use the same port.")
+	relayCmd.PersistentFlags().Bool("logout", true, "true will call logout on exit, this will expire the key or delete if it's ephemeral")
+}
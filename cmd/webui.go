@@ -4,6 +4,8 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"time"
+
 	"github.com/dhouti/tsymbiote/api/webui/tsymbiotewebui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -42,4 +44,15 @@ func init() {
 	webuiCmd.PersistentFlags().Bool("generate-auth", false, "Generate an authkey using the oauth client when starting tsnet")
 	webuiCmd.PersistentFlags().Bool("logout", true, "true will call logout on exit, this will expire the key or delete if it's ephemeral")
 	webuiCmd.PersistentFlags().String("adapter-port", "3621", "The port tsymbiote-adapters are running on, they must all use the same port.")
+	webuiCmd.PersistentFlags().Int("job-adapter-concurrency", 2, "The maximum number of background job calls that can run against a single adapter at once.")
+	webuiCmd.PersistentFlags().Duration("max-request-timeout", time.Minute*2, "The longest timeout a request can ask for when calling an adapter, retries included.")
+	webuiCmd.PersistentFlags().Int("max-request-retries", 5, "The most retries a request can ask for when calling adapters.")
+	webuiCmd.PersistentFlags().Int("max-request-parallelism", 64, "The most adapters a single request will call at once, also the default.")
+	webuiCmd.PersistentFlags().Duration("peermap-snapshot-interval", 0, "How often to snapshot the peer map for history and the change feed, 0 disables snapshots.")
+	webuiCmd.PersistentFlags().Duration("peermap-snapshot-retention", time.Hour*24*7, "How long to keep peer map snapshots.")
+	webuiCmd.PersistentFlags().Duration("recording-retention", time.Hour*24*7, "How long to keep websocket recordings, 0 keeps them forever.")
+	webuiCmd.PersistentFlags().Duration("probe-interval", 0, "How often to ping host pairs and export the results on /metrics, 0 disables the prober.")
+	webuiCmd.PersistentFlags().StringSlice("probe-pairs", []string{}, "A comma separated list of source:target hosts to probe IE: web-1:db-1,web-2:db-1, defaults to every pair of hosts matching probe-selector.")
+	webuiCmd.PersistentFlags().String("probe-selector", "all", "The host selector to build the probe mesh from when probe-pairs isn't set.")
+	webuiCmd.PersistentFlags().String("schedule-file", "", "A YAML file of checks to run on a cron schedule and the webhooks to alert when they change state.")
 }
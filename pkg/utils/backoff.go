@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff returns an exponential delay for a 1 indexed attempt, doubling from base and capped at max.
+// Up to 20% jitter is added so reconnecting clients don't all land on an adapter at the same time.
+func Backoff(attempt int, base time.Duration, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := base
+	for range attempt - 1 {
+		delay *= 2
+		if delay >= max {
+			delay = max
+			break
+		}
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return min(delay+jitter, max)
+}
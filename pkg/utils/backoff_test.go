@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backoff", func() {
+	It("Should double from base with at most 20% jitter", func() {
+		for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second} {
+			for range 50 {
+				delay := Backoff(attempt, time.Second, time.Minute)
+				Expect(delay).To(BeNumerically(">=", want))
+				Expect(delay).To(BeNumerically("<=", want+want/5))
+			}
+		}
+	})
+
+	It("Should never go past max", func() {
+		for _, attempt := range []int{5, 6, 64, 1000} {
+			Expect(Backoff(attempt, time.Second, 10*time.Second)).To(Equal(10 * time.Second))
+		}
+	})
+
+	It("Should treat attempts below 1 as the first", func() {
+		for _, attempt := range []int{0, -3} {
+			delay := Backoff(attempt, time.Second, time.Minute)
+			Expect(delay).To(BeNumerically(">=", time.Second))
+			Expect(delay).To(BeNumerically("<=", time.Second+time.Second/5))
+		}
+	})
+})
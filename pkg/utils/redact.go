@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var redactPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	// Node, disco, machine and lock keys along with auth keys and API tokens.
+	{"key", regexp.MustCompile(`\b(?:nodekey|discokey|mkey|nlpub|nlpriv|privkey|tskey-[a-z]+)[:-][0-9a-zA-Z-]+`)},
+	{"email", regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)},
+	{"ip", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b|\b[0-9a-fA-F]{1,4}(?::[0-9a-fA-F]{0,4}){2,7}\b`)},
+}
+
+// Redactor replaces keys, emails, IP addresses, MagicDNS names and any names registered with AddNames with stable placeholders, IE: <ip-1>.
+// The same value gets the same placeholder for the life of the Redactor so redacted files can still be correlated.
+type Redactor struct {
+	mu     sync.Mutex
+	seen   map[string]string
+	counts map[string]int
+	// names maps lower cased literal values to their placeholder kind.
+	names       map[string]string
+	namePattern *regexp.Regexp
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{
+		seen:   map[string]string{},
+		counts: map[string]int{},
+		names:  map[string]string{},
+	}
+}
+
+// AddNames registers literal values, IE: host names, to be replaced with <kind-N> placeholders wherever they appear.
+// Names are matched case insensitively, a name that was already added keeps its first kind.
+func (r *Redactor) AddNames(kind string, names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == "" {
+			continue
+		}
+		if _, ok := r.names[name]; ok {
+			continue
+		}
+		r.names[name] = kind
+		r.namePattern = nil
+	}
+}
+
+// Placeholder returns the placeholder for a value, IE: to name a directory after a redacted host.
+func (r *Redactor) Placeholder(kind, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if nameKind, ok := r.names[strings.ToLower(value)]; ok {
+		return r.placeholder(nameKind, strings.ToLower(value))
+	}
+	return r.placeholder(kind, value)
+}
+
+func (r *Redactor) Redact(data []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Names go first so a MagicDNS name is replaced whole rather than as its host and tailnet.
+	data = r.compileNames().ReplaceAllFunc(data, func(match []byte) []byte {
+		value := strings.ToLower(string(match))
+		kind, ok := r.names[value]
+		if !ok {
+			kind = "dns"
+		}
+		return []byte(r.placeholder(kind, value))
+	})
+
+	for _, redact := range redactPatterns {
+		data = redact.pattern.ReplaceAllFunc(data, func(match []byte) []byte {
+			value := string(match)
+			// The IPv6 pattern is loose, IE: it matches 12:30:45, only replace real addresses.
+			if redact.kind == "ip" {
+				if _, err := netip.ParseAddr(value); err != nil {
+					return match
+				}
+			}
+			return []byte(r.placeholder(redact.kind, value))
+		})
+	}
+	return data
+}
+
+func (r *Redactor) placeholder(kind, value string) string {
+	placeholder, ok := r.seen[value]
+	if !ok {
+		r.counts[kind]++
+		placeholder = fmt.Sprintf("<%s-%d>", kind, r.counts[kind])
+		r.seen[value] = placeholder
+	}
+	return placeholder
+}
+
+// compileNames builds one pattern for every registered name, longest first so the alternation prefers whole MagicDNS names.
+// Any other MagicDNS name, IE: from a peer that left, is caught by the trailing ts.net match.
+func (r *Redactor) compileNames() *regexp.Regexp {
+	if r.namePattern != nil {
+		return r.namePattern
+	}
+
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	names = append(names, `(?:[a-z0-9-]+\.)+ts\.net`)
+	r.namePattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)\b`)
+	return r.namePattern
+}
@@ -6,16 +6,26 @@ import (
 	"io/fs"
 	"os"
 	"regexp"
+	"slices"
 	"time"
 )
 
-// DiscoverSocket is a retry wrapper around discoverSocket
-func DiscoverSocket() (string, error) {
+// DiscoveredSocket is a tailscaled socket and the PID it was found through.
+type DiscoveredSocket struct {
+	PID  string
+	Path string
+	// OtherNetns is set when the PID isn't in our network namespace, or we can't tell, its localhost isn't ours.
+	OtherNetns bool
+}
+
+// DiscoverSockets is a retry wrapper around discoverSockets.
+// skipped is called for every PID whose socket can't be used, one bad PID doesn't stop discovery.
+func DiscoverSockets(skipped func(pid string, err error)) ([]DiscoveredSocket, error) {
 	// The socket sometimes takes a second to initialize, retry a few times.
-	outPath := ""
+	var sockets []DiscoveredSocket
 	attempts := 0
 	for attempts < 4 {
-		socketPath, err := discoverSocket()
+		discovered, err := discoverSockets(skipped)
 		if err != nil {
 			if os.IsNotExist(err) && attempts < 4 {
 				// Short sleep, try again
@@ -23,26 +33,34 @@ func DiscoverSocket() (string, error) {
 				time.Sleep(time.Millisecond * 150)
 				continue
 			}
-			return outPath, err
+			return sockets, err
 		}
 
-		outPath = socketPath
+		sockets = discovered
 		break
 	}
 
-	return outPath, nil
+	if len(sockets) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return sockets, nil
 }
 
 var socketRegex = regexp.MustCompile("^[0-9]+$")
 
-// discoverSocket looks for PID directories in /proc and checks if there is a tailscaled.sock we can use
+// discoverSockets looks for PID directories in /proc and checks if there is a tailscaled.sock we can use
+// Every process in a container shares its root, a socket is only returned once for the first PID it's found through.
 // This is a bit of a mess, but it works.
-func discoverSocket() (string, error) {
+func discoverSockets(skipped func(pid string, err error)) ([]DiscoveredSocket, error) {
 	files, err := os.ReadDir("/proc")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	selfNetns, selfNetnsErr := os.Stat("/proc/self/ns/net")
+
+	var sockets []DiscoveredSocket
+	var seen []os.FileInfo
 	for _, file := range files {
 		// If a file is a directory and is only numbers, like a PID
 		if file.IsDir() && socketRegex.MatchString(file.Name()) {
@@ -50,20 +68,31 @@ func discoverSocket() (string, error) {
 			socketPath := fmt.Sprintf("/proc/%s/root/tmp/tailscaled.sock", file.Name())
 			fInfo, err := os.Stat(socketPath)
 			if err != nil {
+				// A missing socket is most PIDs, anything else is worth knowing about, IE: permission denied.
 				if !errors.Is(err, os.ErrNotExist) {
-					return "", fmt.Errorf("encountered during socket discovery: %w", err)
+					skipped(file.Name(), err)
 				}
-				// File didn't exist, carry on
 				continue
 			}
 			// Validate that it's a socket
-			if fInfo.Mode().Type() == fs.ModeSocket {
-				return socketPath, nil
-			} else {
-				return "", fmt.Errorf("found matching tailscaled.sock that wasn't a socket")
+			if fInfo.Mode().Type() != fs.ModeSocket {
+				skipped(file.Name(), fmt.Errorf("%s isn't a socket", socketPath))
+				continue
 			}
+
+			if slices.ContainsFunc(seen, func(known os.FileInfo) bool { return os.SameFile(known, fInfo) }) {
+				continue
+			}
+			seen = append(seen, fInfo)
+
+			pidNetns, err := os.Stat(fmt.Sprintf("/proc/%s/ns/net", file.Name()))
+			otherNetns := selfNetnsErr != nil || err != nil || !os.SameFile(selfNetns, pidNetns)
+			sockets = append(sockets, DiscoveredSocket{PID: file.Name(), Path: socketPath, OtherNetns: otherNetns})
 		}
 	}
 
-	return "", os.ErrNotExist
+	if len(sockets) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return sockets, nil
 }
@@ -84,6 +84,11 @@ func (tc *TSClient) getDevices() ([]tailscale.Device, error) {
 	return connectedDevices, nil
 }
 
+// GetDevices returns every device currently connected to control.
+func (tc *TSClient) GetDevices() ([]tailscale.Device, error) {
+	return tc.getDevices()
+}
+
 func (tc *TSClient) GetDevicesWithTag(filterTag string) ([]tailscale.Device, error) {
 	devices, err := tc.getDevices()
 	if err != nil {
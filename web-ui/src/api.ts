@@ -63,8 +63,17 @@ export interface PeermapResponse {
 }
 
 export interface StatusData {
+  // Self is encoded like the Peer entries, times are RFC 3339 strings and keys their text form
   Self?: {
     HostName?: string;
+    PublicKey?: string;
+    Tags?: string[];
+    PrimaryRoutes?: string[];
+    AllowedIPs?: string[];
+    Created?: string;
+    LastWrite?: string;
+    LastSeen?: string;
+    KeyExpiry?: string;
     [key: string]: any;
   };
   Peer?: Record<string, any>;
@@ -133,6 +142,7 @@ export interface PrefsData {
   CorpDNS?: boolean;
   ExitNodeAllowLANAccess?: boolean;
   ExitNodeID?: string;
+  ExitNodeIP?: string;
   ForceDaemon?: boolean;
   LoggedOut?: boolean;
   NetfilterMode?: number;
@@ -391,26 +401,11 @@ export const fetchGoroutines = (hosts: string[]) => fetchHostData<string>('Gorou
 export type WebSocketStreamType = 'Logs' | 'BusEvents';
 
 /**
- * Get WebSocket URL for streaming endpoints
+ * Get WebSocket URL for the multiplexed Stream session, every stream is a subscription on this one socket
  */
-export function getStreamWebSocketUrl(streamType: WebSocketStreamType, hostIds: string[]): string {
+export function getStreamSessionUrl(): string {
   const API_BASE_URL = getApiBaseUrl();
   const wsProtocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
   const wsHost = API_BASE_URL.replace(/^https?:/, wsProtocol);
-  return `${wsHost}/api/${streamType}?hosts=${encodeURIComponent(hostIds.join(','))}`;
+  return `${wsHost}/api/Stream`;
 }
-
-/**
- * Get WebSocket URL for log streaming (legacy, uses getStreamWebSocketUrl)
- */
-export function getLogsWebSocketUrl(hostIds: string[]): string {
-  return getStreamWebSocketUrl('Logs', hostIds);
-}
-
-/**
- * Get WebSocket URL for bus events streaming
- */
-export function getBusEventsWebSocketUrl(hostIds: string[]): string {
-  return getStreamWebSocketUrl('BusEvents', hostIds);
-}
-
@@ -49,10 +49,10 @@ const formatBusEventMessage: MessageFormatter = (decodedMessage: string) => {
 
     // Format: [Count] Type: From -> To
     // Example: [6] magicsock.UDPRelayAllocReq: magicsock.Conn -> [relayserver.extension]
-    const count = event.Count !== undefined ? `[${event.Count}] ` : '';
-    const type = event.Type || 'unknown';
-    const from = event.From || '';
-    const to = Array.isArray(event.To) ? event.To.join(', ') : (event.To || '');
+    const count = event.count !== undefined ? `[${event.count}] ` : '';
+    const type = event.type || 'unknown';
+    const from = event.publisher || '';
+    const to = Array.isArray(event.subscribers) ? event.subscribers.join(', ') : '';
 
     let line = `${count}${type}`;
     if (from || to) {
@@ -105,8 +105,38 @@ const getConnectingMessage = (streamType: StreamType): string => {
   }
 };
 
+// Control messages accepted by the /api/Stream websocket, see stream.go
+interface StreamControl {
+  action: 'subscribe' | 'unsubscribe' | 'pause' | 'resume';
+  host?: string;
+  kind?: string;
+  params?: Record<string, string>;
+  // Names a recording of the subscription, only read on subscribe
+  record?: string;
+}
+
+// Data and status messages sent by the /api/Stream websocket
+interface StreamMessage {
+  type: 'message' | 'status';
+  host?: string;
+  kind?: string;
+  // Base64 encoded adapter message
+  message?: string;
+  status?: string;
+  error?: string;
+  dropped?: number;
+  attempt?: number;
+  // Nanoseconds until the next reconnect attempt
+  retryIn?: number;
+  // Recording ID, sent with the recording status
+  recording?: string;
+}
+
+// One Stream websocket is kept for the whole browser session, each view subscribes and unsubscribes its hosts on it.
 export function useWebSocketStream(): UseWebSocketStreamReturn {
   const wsRef = useRef<WebSocket | null>(null);
+  // Controls sent before the socket finished opening
+  const pendingRef = useRef<StreamControl[]>([]);
   const hostIdsRef = useRef<string[]>([]);
   const lastPongTime = useRef<number>(0);
   const pingIntervalRef = useRef<ReturnType<typeof setInterval> | null>(null);
@@ -116,32 +146,176 @@ export function useWebSocketStream(): UseWebSocketStreamReturn {
   const [isStreaming, setIsStreaming] = useState(false);
   const [activeStreamType, setActiveStreamType] = useState<StreamType | null>(null);
 
-  const cancelStreaming = useCallback((appendDisconnectMessage = true) => {
-    // Clear ping interval
+  const appendToHost = useCallback((hostId: string, text: string) => {
+    setStreamData(prev => ({ ...prev, [hostId]: (prev[hostId] || '') + text }));
+  }, []);
+
+  const resetStream = useCallback(() => {
+    hostIdsRef.current = [];
+    streamTypeRef.current = null;
+    setIsStreaming(false);
+    setActiveStreamType(null);
+  }, []);
+
+  const sendControl = useCallback((control: StreamControl) => {
+    const ws = wsRef.current;
+    if (ws && ws.readyState === WebSocket.OPEN) {
+      ws.send(JSON.stringify(control));
+      return;
+    }
+    pendingRef.current.push(control);
+  }, []);
+
+  const closeSession = useCallback((reason: string) => {
     if (pingIntervalRef.current) {
       clearInterval(pingIntervalRef.current);
       pingIntervalRef.current = null;
     }
     lastPongTime.current = 0;
+    pendingRef.current = [];
+
+    const ws = wsRef.current;
+    wsRef.current = null;
+    if (ws && (ws.readyState === WebSocket.OPEN || ws.readyState === WebSocket.CONNECTING)) {
+      console.log('Closing Stream WebSocket:', reason);
+      ws.close(1000, reason);
+    }
+  }, []);
+
+  const handleMessage = useCallback((event: MessageEvent) => {
+    // Handle pong response for keepalive
+    if (event.data === 'pong') {
+      lastPongTime.current = Date.now();
+      return;
+    }
+
+    let streamMessage: StreamMessage;
+    try {
+      streamMessage = JSON.parse(event.data);
+    } catch (e) {
+      console.error('Error parsing Stream message:', e, 'raw data:', event.data);
+      return;
+    }
+
+    const streamType = streamTypeRef.current;
+    const hostId = streamMessage.host;
+    // Late messages from a view we already left
+    if (!streamType || streamMessage.kind !== streamType || !hostId || !hostIdsRef.current.includes(hostId)) {
+      if (streamMessage.type === 'status' && streamMessage.status === 'error' && !hostId) {
+        console.error('Stream error:', streamMessage.error);
+      }
+      return;
+    }
+
+    if (streamMessage.type === 'message') {
+      try {
+        const decodedMessage = atob(streamMessage.message || '');
+        const formattedMessage = getMessageFormatter(streamType)(decodedMessage);
+        if (formattedMessage !== null) {
+          appendToHost(hostId, formattedMessage);
+        }
+      } catch (e) {
+        console.error(`Error decoding ${streamType} message:`, e);
+      }
+      return;
+    }
+
+    switch (streamMessage.status) {
+      case 'subscribed':
+        // Drop the connecting message once the adapter answers
+        setStreamData(prev => (prev[hostId] === getConnectingMessage(streamType) ? { ...prev, [hostId]: '' } : prev));
+        break;
+      case 'adapter disconnected':
+        appendToHost(hostId, getDisconnectMessage(streamType));
+        break;
+      case 'reconnecting': {
+        const retryIn = streamMessage.retryIn ? ` in ${(streamMessage.retryIn / 1e9).toFixed(1)}s` : '';
+        appendToHost(hostId, `\n[reconnecting${retryIn}, attempt ${streamMessage.attempt ?? 0}]`);
+        break;
+      }
+      case 'error':
+        appendToHost(hostId, `\n[error: ${streamMessage.error || 'unknown'}]`);
+        break;
+    }
+  }, [appendToHost]);
+
+  const openSession = useCallback(() => {
+    const existing = wsRef.current;
+    if (existing && (existing.readyState === WebSocket.OPEN || existing.readyState === WebSocket.CONNECTING)) {
+      return;
+    }
+
+    const wsUrl = api.getStreamSessionUrl();
+    console.log('Connecting to Stream WebSocket:', wsUrl);
+    const ws = new WebSocket(wsUrl);
+    wsRef.current = ws;
+
+    ws.onopen = () => {
+      console.log('Stream WebSocket connected');
+      lastPongTime.current = Date.now();
+      const pending = pendingRef.current;
+      pendingRef.current = [];
+      pending.forEach(control => ws.send(JSON.stringify(control)));
+    };
+
+    ws.onmessage = handleMessage;
+
+    ws.onerror = (error) => {
+      console.error('Stream WebSocket error:', error);
+      // Don't display error - close or timeout handler will report it
+    };
 
+    ws.onclose = (event) => {
+      console.log('Stream WebSocket closed:', event.code, event.reason);
+      // Ignore sockets we already replaced or closed ourselves
+      if (wsRef.current !== ws) {
+        return;
+      }
+      closeSession('Socket closed');
+      if (streamTypeRef.current) {
+        const disconnectMsg = getDisconnectMessage(streamTypeRef.current);
+        hostIdsRef.current.forEach(hostId => appendToHost(hostId, disconnectMsg));
+      }
+      resetStream();
+    };
+
+    // The server drops sessions that stop pinging, keep pinging for as long as the socket is open
+    pingIntervalRef.current = setInterval(() => {
+      if (wsRef.current !== ws) return;
+
+      if (lastPongTime.current > 0 && Date.now() - lastPongTime.current > PONG_TIMEOUT) {
+        console.log('Stream connection timeout');
+        hostIdsRef.current.forEach(hostId => appendToHost(hostId, '\n[connection timeout]'));
+        closeSession('Connection timeout');
+        resetStream();
+        return;
+      }
+
+      if (ws.readyState === WebSocket.OPEN) {
+        ws.send('ping');
+      }
+    }, PING_INTERVAL);
+  }, [appendToHost, closeSession, handleMessage, resetStream]);
+
+  const unsubscribeAll = useCallback(() => {
+    const streamType = streamTypeRef.current;
+    if (!streamType) return;
+    hostIdsRef.current.forEach(hostId => {
+      sendControl({ action: 'unsubscribe', host: hostId, kind: streamType });
+    });
+  }, [sendControl]);
+
+  const cancelStreaming = useCallback((appendDisconnectMessage = true) => {
     // Append disconnect message if requested
-    if (appendDisconnectMessage && wsRef.current && wsRef.current.readyState === WebSocket.OPEN && streamTypeRef.current) {
+    if (appendDisconnectMessage && streamTypeRef.current) {
       const disconnectMsg = getDisconnectMessage(streamTypeRef.current);
-      hostIdsRef.current.forEach(hostId => {
-        setStreamData(prev => ({ ...prev, [hostId]: (prev[hostId] || '') + disconnectMsg }));
-      });
+      hostIdsRef.current.forEach(hostId => appendToHost(hostId, disconnectMsg));
     }
 
-    if (wsRef.current && wsRef.current.readyState === WebSocket.OPEN) {
-      console.log('Closing WebSocket connection for hosts:', hostIdsRef.current.join(','));
-      wsRef.current.close(1000, 'Client closing');
-    }
-    wsRef.current = null;
-    hostIdsRef.current = [];
-    streamTypeRef.current = null;
-    setIsStreaming(false);
-    setActiveStreamType(null);
-  }, []);
+    // The socket stays open for the next view, only the subscriptions go away
+    unsubscribeAll();
+    resetStream();
+  }, [appendToHost, resetStream, unsubscribeAll]);
 
   const startStream = useCallback((streamType: StreamType, hostIds: string[]) => {
     // Cancel any existing stream
@@ -151,7 +325,6 @@ export function useWebSocketStream(): UseWebSocketStreamReturn {
       return;
     }
 
-    setStreamData({});
     setIsStreaming(true);
     setActiveStreamType(streamType);
     hostIdsRef.current = hostIds;
@@ -165,134 +338,21 @@ export function useWebSocketStream(): UseWebSocketStreamReturn {
     });
     setStreamData(initialData);
 
-    try {
-      console.log(`Starting WebSocket ${streamType} stream for hosts:`, hostIds.join(','));
-
-      const wsUrl = api.getStreamWebSocketUrl(streamType, hostIds);
-      console.log('Connecting to WebSocket:', wsUrl);
-
-      const ws = new WebSocket(wsUrl);
-      wsRef.current = ws;
-      const messageFormatter = getMessageFormatter(streamType);
-      const disconnectMsg = getDisconnectMessage(streamType);
-
-      ws.onopen = () => {
-        console.log(`WebSocket connected for ${streamType}, hosts:`, hostIds.join(','));
-        const clearedData: Record<string, string> = {};
-        hostIds.forEach(hostId => {
-          clearedData[hostId] = '';
-        });
-        setStreamData(clearedData);
-      };
-
-      ws.onmessage = (event) => {
-        // Handle pong response for keepalive
-        if (event.data === 'pong') {
-          lastPongTime.current = Date.now();
-          return;
-        }
-
-        try {
-          // Parse the outer message containing host and base64-encoded message
-          const wsMessage = JSON.parse(event.data);
-          const hostId = wsMessage.Host;
-          const encodedMessage = wsMessage.Message;
-
-          console.log(`Received WebSocket ${streamType} message for host:`, hostId, 'length:', event.data.length);
-
-          // Decode the base64-encoded message
-          const decodedMessage = atob(encodedMessage);
-
-          // Handle "websocket closed" message for a specific host
-          if (decodedMessage === 'websocket closed') {
-            setStreamData(prev => ({ ...prev, [hostId]: (prev[hostId] || '') + disconnectMsg }));
-            return;
-          }
-
-          // Format the message using the stream-specific formatter
-          const formattedMessage = messageFormatter(decodedMessage);
-          if (formattedMessage !== null) {
-            setStreamData(prev => ({ ...prev, [hostId]: (prev[hostId] || '') + formattedMessage }));
-          }
-        } catch (e) {
-          // If parsing fails, log error for debugging
-          console.error(`Error parsing WebSocket ${streamType} message:`, e, 'raw data:', event.data);
-        }
-      };
-
-      ws.onerror = (error) => {
-        console.error('WebSocket error:', error);
-        // Don't display error - timeout handler will show [connection timeout] if needed
-      };
-
-      ws.onclose = (event) => {
-        console.log('WebSocket closed:', event.code, event.reason);
-        // Clean up if not already handled by timeout
-        if (wsRef.current) {
-          lastPongTime.current = 0;
-          wsRef.current = null;
-          hostIdsRef.current = [];
-          streamTypeRef.current = null;
-          setIsStreaming(false);
-          setActiveStreamType(null);
-        }
-      };
-
-    } catch (err) {
-      console.error(`Error setting up WebSocket for ${streamType}:`, err);
-      // Don't display error in stream pane
-    }
-
-    // Start ping interval to detect stale connections
-    pingIntervalRef.current = setInterval(() => {
-      const now = Date.now();
-      const ws = wsRef.current;
-
-      if (!ws) return;
-
-      // Check if we haven't received a pong in too long
-      if (lastPongTime.current > 0 && now - lastPongTime.current > PONG_TIMEOUT) {
-        console.log('Connection timeout');
-        hostIdsRef.current.forEach(hostId => {
-          setStreamData(prev => ({ ...prev, [hostId]: (prev[hostId] || '') + '\n[connection timeout]' }));
-        });
-        wsRef.current = null;
-        lastPongTime.current = 0;
-        hostIdsRef.current = [];
-        streamTypeRef.current = null;
-        ws.close(1000, 'Connection timeout');
-        setIsStreaming(false);
-        setActiveStreamType(null);
-        return;
-      }
-
-      // Send ping if connection is open
-      if (ws.readyState === WebSocket.OPEN) {
-        ws.send('ping');
-        // Initialize lastPongTime on first ping if not set
-        if (lastPongTime.current === 0) {
-          lastPongTime.current = now;
-        }
-      }
-    }, PING_INTERVAL);
-  }, [cancelStreaming]);
+    console.log(`Subscribing to ${streamType} for hosts:`, hostIds.join(','));
+    openSession();
+    hostIds.forEach(hostId => {
+      sendControl({ action: 'subscribe', host: hostId, kind: streamType });
+    });
+  }, [cancelStreaming, openSession, sendControl]);
 
   // Cleanup on unmount
   useEffect(() => {
     return () => {
-      if (pingIntervalRef.current) {
-        clearInterval(pingIntervalRef.current);
-      }
-      if (wsRef.current) {
-        console.log('Component unmounting, closing WebSocket for hosts:', hostIdsRef.current.join(','));
-        wsRef.current.close(1000, 'Component unmounting');
-        wsRef.current = null;
-      }
+      closeSession('Component unmounting');
       hostIdsRef.current = [];
       streamTypeRef.current = null;
-      lastPongTime.current = 0;
     };
-  }, []);
+  }, [closeSession]);
 
   return {
     streamData,